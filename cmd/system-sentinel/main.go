@@ -13,6 +13,8 @@ import (
 	"system-sentinel/internal/config"
 	"system-sentinel/internal/logging"
 	"system-sentinel/internal/metrics"
+	"system-sentinel/internal/plugins"
+	"system-sentinel/internal/probes"
 	"system-sentinel/internal/scripts"
 	"system-sentinel/internal/spikes"
 	"system-sentinel/internal/storage"
@@ -40,7 +42,12 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	collector := metrics.NewCollector(cfg.Interface)
+	registry := metrics.NewRegistry(cfg)
+	plugins.Register(registry, cfg)
+	probes.Register(registry, cfg)
+	for _, err := range registry.Init() {
+		log.Printf("source disabled: %v", err)
+	}
 	spikeDetector := spikes.NewDetector(cfg)
 	alertEngine := alerts.NewEngine(cfg)
 	logger, err := logging.NewLogger(cfg.LogDir)
@@ -69,10 +76,14 @@ func main() {
 		case <-sigCh:
 			return
 		case <-ticker.C:
-			snap, err := collector.Collect()
-			if err != nil {
-				log.Printf("metrics collect error: %v", err)
-				continue
+			snap := metrics.MetricsSnapshot{Timestamp: time.Now()}
+			for _, source := range registry.Sources() {
+				samples, err := source.Collect(&snap)
+				if err != nil {
+					log.Printf("%s source error: %v", source.Name(), err)
+					continue
+				}
+				snap.Samples = append(snap.Samples, samples...)
 			}
 
 			spikeTypes := spikeDetector.Detect(snap, lastSnapshot)
@@ -83,17 +94,56 @@ func main() {
 			}
 
 			alertTypes := alertEngine.Detect(snap, lastSnapshot)
+			if pending := alertEngine.NewlyPending(); len(pending) > 0 {
+				if err := logger.LogPending(snap, pending); err != nil {
+					log.Printf("log pending error: %v", err)
+				}
+			}
+			started, stopped := alertEngine.FlappingChanges()
+			if len(started) > 0 {
+				if err := logger.LogFlapping(snap, started, true); err != nil {
+					log.Printf("log flapping error: %v", err)
+				}
+			}
+			if len(stopped) > 0 {
+				if err := logger.LogFlapping(snap, stopped, false); err != nil {
+					log.Printf("log flapping error: %v", err)
+				}
+			}
+			for _, resolved := range alertEngine.Resolved() {
+				if err := logger.LogResolved(snap, resolved.Type, resolved.Severity, resolved.FiredAt, resolved.Duration); err != nil {
+					log.Printf("log resolved error: %v", err)
+				}
+
+				if cfg.Scripts.Enabled && resolved.Notified && !resolved.Flapping {
+					go func(r alerts.Resolution, snapshot metrics.MetricsSnapshot) {
+						if err := scriptRunner.ExecuteResolved(r.Type, r.Severity, r.FiredAt, r.Duration, snapshot); err != nil {
+							log.Printf("script execution error: %v", err)
+						}
+					}(resolved, snap)
+				}
+			}
+
 			if len(alertTypes) > 0 {
-				if err := logger.LogAlert(snap, alertTypes); err != nil {
+				if cfg.TopProcesses.Enabled && snap.TopProcesses == nil {
+					top, err := registry.TopProcesses()
+					if err != nil {
+						log.Printf("top processes error: %v", err)
+					}
+					snap.TopProcesses = top
+				}
+
+				severities := alertEngine.Severities()
+				if err := logger.LogAlert(snap, alertTypes, severities); err != nil {
 					log.Printf("log alert error: %v", err)
 				}
 
 				if cfg.Scripts.Enabled && alertEngine.ShouldExecuteScripts(alertTypes) {
-					go func(types []string, snapshot metrics.MetricsSnapshot) {
-						if err := scriptRunner.Execute(types, snapshot); err != nil {
+					go func(types []string, severities map[string]string, snapshot, previous metrics.MetricsSnapshot) {
+						if err := scriptRunner.Execute(types, severities, snapshot, previous); err != nil {
 							log.Printf("script execution error: %v", err)
 						}
-					}(alertTypes, snap)
+					}(alertTypes, severities, snap, lastSnapshot)
 				}
 			}
 
@@ -6,45 +6,89 @@ import (
 
 	"system-sentinel/internal/config"
 	"system-sentinel/internal/metrics"
+	"system-sentinel/internal/rules"
 )
 
 type Engine struct {
-	cfg       *config.Config
-	lastFired map[string]time.Time
-	mu        sync.RWMutex
+	cfg   *config.Config
+	rules []config.Rule
+	clear []config.Rule
+	tiers []tier
+
+	lastFired  map[string]time.Time
+	states     map[string]*alertState
+	newPending []string
+	resolved   []Resolution
+
+	changes     map[string][]time.Time
+	flapping    map[string]bool
+	flapStarted []string
+	flapStopped []string
+
+	mu sync.RWMutex
+}
+
+// tier runs the alert rules with the thresholds and for_sec of one
+// severity tier, tracking its own pending types.
+type tier struct {
+	severity string
+	engine   *Engine
 }
 
 func NewEngine(cfg *config.Config) *Engine {
+	all := cfg.AlertRules()
+	e := newEngine(cfg, config.Variant(all, ""))
+	e.clear = config.Variant(all, config.ClearVariant)
+	for _, level := range config.SeverityLevels {
+		if rules := config.Variant(all, level); len(rules) > 0 {
+			e.tiers = append(e.tiers, tier{severity: level, engine: newEngine(cfg, rules)})
+		}
+	}
+	return e
+}
+
+func newEngine(cfg *config.Config, rules []config.Rule) *Engine {
 	return &Engine{
 		cfg:       cfg,
+		rules:     rules,
 		lastFired: make(map[string]time.Time),
+		states:    make(map[string]*alertState),
+		changes:   make(map[string][]time.Time),
+		flapping:  make(map[string]bool),
 	}
 }
 
+// Detect returns the alert types firing for current and advances each
+// type's lifecycle. A rule with for_sec only fires once its condition has
+// held on every sample for that long; until then its types are pending.
+// A firing type keeps firing while its rule still matches with the clear
+// thresholds. Firing types whose condition cleared are reported by
+// Resolved, and the severity of each firing type by Severities.
 func (e *Engine) Detect(current, previous metrics.MetricsSnapshot) []string {
-	var alerts []string
-
-	if e.cfg.Alerts.CPU.Enabled {
-		if e.detectCPUAlert(current, previous) {
-			alerts = append(alerts, "cpu")
+	matches := e.detect(current, previous)
+	held := rules.Matches(e.clear, current, previous)
+
+	// Tiers run from lowest to highest, so each type ends up with the
+	// highest tier firing for it.
+	escalated := make(map[string]string)
+	for _, t := range e.tiers {
+		for _, alertType := range t.engine.advance(current.Timestamp, t.engine.detect(current, previous), nil, nil) {
+			escalated[alertType] = t.severity
 		}
 	}
 
-	if e.cfg.Alerts.Memory.Enabled {
-		if e.detectMemoryAlert(current) {
-			alerts = append(alerts, "memory")
-		}
-	}
-
-	if e.cfg.Alerts.Network.Enabled {
-		if e.detectNetworkAlert(current) {
-			alerts = append(alerts, "network")
-		}
-	}
+	return e.advance(current.Timestamp, matches, held, escalated)
+}
 
-	return alerts
+// detect returns the alert types whose rules match current.
+func (e *Engine) detect(current, previous metrics.MetricsSnapshot) []rules.Match {
+	return rules.Matches(e.rules, current, previous)
 }
 
+// ShouldExecuteScripts reports whether any of the alert types is due for a
+// script run: not flapping, and outside its debounce window or at a higher
+// severity than on the previous sample. When it is, every type is marked as
+// notified, since the run reports them all.
 func (e *Engine) ShouldExecuteScripts(alertTypes []string) bool {
 	if len(alertTypes) == 0 {
 		return false
@@ -58,39 +102,28 @@ func (e *Engine) ShouldExecuteScripts(alertTypes []string) bool {
 
 	shouldExecute := false
 	for _, alertType := range alertTypes {
+		if e.flapping[alertType] {
+			continue
+		}
+		escalated := false
+		if state, ok := e.states[alertType]; ok && state.previous != "" {
+			escalated = config.SeverityRank(state.severity) > config.SeverityRank(state.previous)
+		}
+
 		lastTime, exists := e.lastFired[alertType]
-		if !exists || now.Sub(lastTime) >= debounceDur {
+		if !exists || now.Sub(lastTime) >= debounceDur || escalated {
 			shouldExecute = true
 			e.lastFired[alertType] = now
 		}
 	}
 
-	return shouldExecute
-}
-
-func (e *Engine) detectCPUAlert(current, previous metrics.MetricsSnapshot) bool {
-	cfg := e.cfg.Alerts.CPU
-
-	if current.CPUUsagePercent >= cfg.AbsoluteThreshold {
-		return true
-	}
-
-	if previous.CPUUsagePercent > 0 && cfg.RelativeThreshold > 0 {
-		relativeChange := ((current.CPUUsagePercent - previous.CPUUsagePercent) / previous.CPUUsagePercent) * 100.0
-		if relativeChange >= cfg.RelativeThreshold {
-			return true
+	if shouldExecute {
+		for _, alertType := range alertTypes {
+			if state, ok := e.states[alertType]; ok {
+				state.notified = true
+			}
 		}
 	}
 
-	return false
-}
-
-func (e *Engine) detectMemoryAlert(current metrics.MetricsSnapshot) bool {
-	cfg := e.cfg.Alerts.Memory
-	return current.MemUsedPercent >= cfg.AbsoluteThreshold
-}
-
-func (e *Engine) detectNetworkAlert(current metrics.MetricsSnapshot) bool {
-	cfg := e.cfg.Alerts.Network
-	return current.NetRxMbps >= cfg.RxMbpsThreshold || current.NetTxMbps >= cfg.TxMbpsThreshold
+	return shouldExecute
 }
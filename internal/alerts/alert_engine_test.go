@@ -0,0 +1,474 @@
+package alerts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+func loadConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// cpuSnapshot returns a snapshot taken sec seconds in with the given
+// overall and cpu0 usage.
+func cpuSnapshot(sec int, usage, core float64) metrics.MetricsSnapshot {
+	return metrics.MetricsSnapshot{
+		Timestamp:       time.Unix(1700000000+int64(sec), 0),
+		CPUUsagePercent: usage,
+		CPUCores:        []metrics.CPUCoreStats{{Name: "cpu0", UsagePercent: core}},
+	}
+}
+
+// TestSeverityTierOnlyCoversItsThresholds checks that a tier setting one
+// threshold of a section does not escalate the types its other thresholds
+// raise.
+func TestSeverityTierOnlyCoversItsThresholds(t *testing.T) {
+	cfg := loadConfig(t, `
+alerts:
+  cpu:
+    enabled: true
+    absolute_threshold: 75
+    relative_threshold: 50
+    core_threshold: 80
+    severity: warning
+    severities:
+      critical: {absolute_threshold: 95}
+`)
+
+	tests := []struct {
+		name           string
+		previous       metrics.MetricsSnapshot
+		current        metrics.MetricsSnapshot
+		wantSeverities map[string]string
+	}{
+		{
+			name:     "relative and core below the tier",
+			previous: cpuSnapshot(0, 20, 20),
+			current:  cpuSnapshot(1, 40, 85),
+			wantSeverities: map[string]string{
+				"cpu":           "warning",
+				"cpu_core:cpu0": "warning",
+			},
+		},
+		{
+			name:     "absolute above the tier",
+			previous: cpuSnapshot(0, 90, 20),
+			current:  cpuSnapshot(1, 96, 85),
+			wantSeverities: map[string]string{
+				"cpu":           "critical",
+				"cpu_core:cpu0": "warning",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(cfg)
+			e.Detect(tt.current, tt.previous)
+			if got := e.Severities(); !reflect.DeepEqual(got, tt.wantSeverities) {
+				t.Errorf("Severities() = %v, want %v", got, tt.wantSeverities)
+			}
+		})
+	}
+}
+
+// TestNetworkTypes checks that a config with only the single interface
+// setting keeps the v1.0.0 network type, where a threshold of 0 fires, and
+// that an interfaces list raises network:<iface> with 0 disabling a check.
+func TestNetworkTypes(t *testing.T) {
+	snap := metrics.MetricsSnapshot{
+		Timestamp:  time.Unix(1700000000, 0),
+		Interfaces: []metrics.InterfaceStats{{Name: "eth0", TxMbps: 200}, {Name: "eth1", TxMbps: 200}},
+	}
+
+	tests := []struct {
+		name      string
+		config    string
+		wantTypes []string
+	}{
+		{
+			name: "single interface",
+			config: `
+interface: eth0
+alerts:
+  network: {enabled: true, rx_mbps_threshold: 0, tx_mbps_threshold: 1000}
+`,
+			wantTypes: []string{"network"},
+		},
+		{
+			name: "single interface override",
+			config: `
+interface: eth0
+alerts:
+  network:
+    enabled: true
+    rx_mbps_threshold: 1000
+    tx_mbps_threshold: 1000
+    interfaces:
+      "eth*": {rx_mbps_threshold: 1000, tx_mbps_threshold: 100}
+`,
+			wantTypes: []string{"network"},
+		},
+		{
+			name: "interfaces list",
+			config: `
+interfaces: [eth0, eth1]
+alerts:
+  network: {enabled: true, rx_mbps_threshold: 0, tx_mbps_threshold: 100}
+`,
+			wantTypes: []string{"network:eth0", "network:eth1"},
+		},
+		{
+			name: "interfaces list with 0 thresholds",
+			config: `
+interfaces: [eth0, eth1]
+alerts:
+  network: {enabled: true, rx_mbps_threshold: 0, tx_mbps_threshold: 0}
+`,
+			wantTypes: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(loadConfig(t, tt.config))
+			if got := e.Detect(snap, metrics.MetricsSnapshot{}); !reflect.DeepEqual(got, tt.wantTypes) {
+				t.Errorf("Detect() = %v, want %v", got, tt.wantTypes)
+			}
+		})
+	}
+}
+
+// TestCPUIncludeIOWait checks that include_iowait makes the cpu usage
+// thresholds count iowait as busy.
+func TestCPUIncludeIOWait(t *testing.T) {
+	snap := metrics.MetricsSnapshot{
+		Timestamp:       time.Unix(1700000000, 0),
+		CPUUsagePercent: 30,
+		CPUTimes:        metrics.CPUTimes{IOWaitPercent: 60},
+	}
+
+	tests := []struct {
+		name          string
+		includeIOWait string
+		wantTypes     []string
+	}{
+		{name: "iowait as idle", includeIOWait: "false", wantTypes: nil},
+		{name: "iowait as busy", includeIOWait: "true", wantTypes: []string{"cpu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(loadConfig(t, `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80, include_iowait: `+tt.includeIOWait+`}
+`))
+			if got := e.Detect(snap, metrics.MetricsSnapshot{}); !reflect.DeepEqual(got, tt.wantTypes) {
+				t.Errorf("Detect() = %v, want %v", got, tt.wantTypes)
+			}
+		})
+	}
+}
+
+// step is one sample fed to an engine, with the firing and newly pending
+// types expected after it.
+type step struct {
+	sec         int
+	usage       float64
+	wantFiring  []string
+	wantPending []string
+}
+
+// run feeds steps to e in order, checking each one.
+func run(t *testing.T, e *Engine, steps []step) {
+	t.Helper()
+	previous := metrics.MetricsSnapshot{}
+	for _, s := range steps {
+		current := cpuSnapshot(s.sec, s.usage, 0)
+		if got := e.Detect(current, previous); !reflect.DeepEqual(got, s.wantFiring) {
+			t.Errorf("at %ds: Detect() = %v, want %v", s.sec, got, s.wantFiring)
+		}
+		if got := e.NewlyPending(); !reflect.DeepEqual(got, s.wantPending) {
+			t.Errorf("at %ds: NewlyPending() = %v, want %v", s.sec, got, s.wantPending)
+		}
+		previous = current
+	}
+}
+
+// TestForSec checks that a rule with for_sec is pending until its
+// condition has held for that long, and starts over when it breaks.
+func TestForSec(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		steps  []step
+	}{
+		{
+			name: "fires at once without for_sec",
+			config: `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80}
+`,
+			steps: []step{
+				{sec: 0, usage: 90, wantFiring: []string{"cpu"}},
+				{sec: 1, usage: 50},
+			},
+		},
+		{
+			name: "pending until for_sec",
+			config: `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80, for_sec: 10}
+`,
+			steps: []step{
+				{sec: 0, usage: 90, wantPending: []string{"cpu"}},
+				{sec: 5, usage: 90},
+				{sec: 10, usage: 90, wantFiring: []string{"cpu"}},
+				{sec: 11, usage: 90, wantFiring: []string{"cpu"}},
+			},
+		},
+		{
+			name: "a dip starts over",
+			config: `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80, for_sec: 10}
+`,
+			steps: []step{
+				{sec: 0, usage: 90, wantPending: []string{"cpu"}},
+				{sec: 5, usage: 50},
+				{sec: 8, usage: 90, wantPending: []string{"cpu"}},
+				{sec: 15, usage: 90},
+				{sec: 18, usage: 90, wantFiring: []string{"cpu"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, NewEngine(loadConfig(t, tt.config)), tt.steps)
+		})
+	}
+}
+
+// TestResolved checks that a firing type whose condition cleared is
+// reported once, with its firing time, duration and severity, and that a
+// type that never fired is not.
+func TestResolved(t *testing.T) {
+	cfg := loadConfig(t, `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80, severity: warning}
+  memory: {enabled: true, absolute_threshold: 80, for_sec: 60}
+`)
+
+	tests := []struct {
+		name   string
+		notify bool
+		mem    float64
+		want   []Resolution
+	}{
+		{
+			name:   "notified",
+			notify: true,
+			want: []Resolution{{
+				Type: "cpu", Severity: "warning", FiredAt: time.Unix(1700000000, 0),
+				Duration: 30 * time.Second, Notified: true,
+			}},
+		},
+		{
+			name: "not notified",
+			want: []Resolution{{
+				Type: "cpu", Severity: "warning", FiredAt: time.Unix(1700000000, 0),
+				Duration: 30 * time.Second,
+			}},
+		},
+		{
+			name: "pending type cleared",
+			mem:  90,
+			want: []Resolution{{
+				Type: "cpu", Severity: "warning", FiredAt: time.Unix(1700000000, 0),
+				Duration: 30 * time.Second,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(cfg)
+
+			firing := cpuSnapshot(0, 90, 0)
+			firing.MemUsedPercent = tt.mem
+			alertTypes := e.Detect(firing, metrics.MetricsSnapshot{})
+			if tt.notify && !e.ShouldExecuteScripts(alertTypes) {
+				t.Fatalf("ShouldExecuteScripts(%v) = false, want true", alertTypes)
+			}
+			if got := e.Resolved(); got != nil {
+				t.Errorf("Resolved() while firing = %v, want none", got)
+			}
+
+			cleared := cpuSnapshot(30, 50, 0)
+			e.Detect(cleared, firing)
+			if got := e.Resolved(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolved() = %+v, want %+v", got, tt.want)
+			}
+
+			e.Detect(cpuSnapshot(31, 50, 0), cleared)
+			if got := e.Resolved(); got != nil {
+				t.Errorf("Resolved() after resolving = %v, want none", got)
+			}
+		})
+	}
+}
+
+// TestClear checks that a firing type keeps firing while its condition
+// holds with the clear thresholds, and that clear thresholds do not make a
+// type fire.
+func TestClear(t *testing.T) {
+	cfg := `
+alerts:
+  cpu:
+    enabled: true
+    absolute_threshold: 80
+    clear: {absolute_threshold: 70}
+`
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "held above the clear threshold",
+			steps: []step{
+				{sec: 0, usage: 85, wantFiring: []string{"cpu"}},
+				{sec: 1, usage: 75, wantFiring: []string{"cpu"}},
+				{sec: 2, usage: 70, wantFiring: []string{"cpu"}},
+				{sec: 3, usage: 65},
+			},
+		},
+		{
+			name: "clear threshold alone does not fire",
+			steps: []step{
+				{sec: 0, usage: 75},
+				{sec: 1, usage: 85, wantFiring: []string{"cpu"}},
+				{sec: 2, usage: 60},
+				{sec: 3, usage: 75},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, NewEngine(loadConfig(t, cfg)), tt.steps)
+		})
+	}
+}
+
+// TestFlapping checks that a type changing state more than max_changes
+// times in the window is marked flapping, stops running scripts, and is
+// unmarked once the window passes without changes.
+func TestFlapping(t *testing.T) {
+	cfg := loadConfig(t, `
+alerts:
+  cpu: {enabled: true, absolute_threshold: 80}
+  flapping: {enabled: true, max_changes: 2, window_sec: 60}
+`)
+	e := NewEngine(cfg)
+
+	steps := []struct {
+		sec         int
+		usage       float64
+		wantStarted []string
+		wantStopped []string
+		wantScripts bool
+	}{
+		{sec: 0, usage: 90, wantScripts: true},
+		{sec: 10, usage: 50},
+		{sec: 20, usage: 90, wantStarted: []string{"cpu"}},
+		{sec: 30, usage: 50},
+		{sec: 40, usage: 90},
+		{sec: 99, usage: 90},
+		{sec: 100, usage: 90, wantStopped: []string{"cpu"}, wantScripts: true},
+	}
+
+	previous := metrics.MetricsSnapshot{}
+	for _, s := range steps {
+		current := cpuSnapshot(s.sec, s.usage, 0)
+		alertTypes := e.Detect(current, previous)
+		started, stopped := e.FlappingChanges()
+		if !reflect.DeepEqual(started, s.wantStarted) || !reflect.DeepEqual(stopped, s.wantStopped) {
+			t.Errorf("at %ds: FlappingChanges() = %v, %v, want %v, %v", s.sec, started, stopped, s.wantStarted, s.wantStopped)
+		}
+		// Clear the debounce window, so only flapping holds scripts back.
+		e.lastFired = make(map[string]time.Time)
+		if got := e.ShouldExecuteScripts(alertTypes); got != s.wantScripts {
+			t.Errorf("at %ds: ShouldExecuteScripts(%v) = %v, want %v", s.sec, alertTypes, got, s.wantScripts)
+		}
+		previous = current
+	}
+}
+
+// TestSeverityTiers checks that a type takes the highest tier whose
+// thresholds held for the tier's for_sec, that an escalation runs scripts
+// inside the debounce window, and that the resolution carries the highest
+// severity reached.
+func TestSeverityTiers(t *testing.T) {
+	cfg := loadConfig(t, `
+scripts: {debounce_sec: 3600}
+alerts:
+  cpu:
+    enabled: true
+    absolute_threshold: 75
+    severity: warning
+    severities:
+      critical: {absolute_threshold: 90, for_sec: 20}
+      page: {absolute_threshold: 98}
+`)
+	e := NewEngine(cfg)
+
+	steps := []struct {
+		sec            int
+		usage          float64
+		wantSeverities map[string]string
+		wantScripts    bool
+	}{
+		{sec: 0, usage: 80, wantSeverities: map[string]string{"cpu": "warning"}, wantScripts: true},
+		{sec: 10, usage: 95, wantSeverities: map[string]string{"cpu": "warning"}},
+		{sec: 30, usage: 95, wantSeverities: map[string]string{"cpu": "critical"}, wantScripts: true},
+		{sec: 40, usage: 99, wantSeverities: map[string]string{"cpu": "page"}, wantScripts: true},
+		{sec: 50, usage: 80, wantSeverities: map[string]string{"cpu": "warning"}},
+		{sec: 60, usage: 95, wantSeverities: map[string]string{"cpu": "warning"}},
+		{sec: 70, usage: 50, wantSeverities: map[string]string{}},
+	}
+
+	previous := metrics.MetricsSnapshot{}
+	for _, s := range steps {
+		current := cpuSnapshot(s.sec, s.usage, 0)
+		alertTypes := e.Detect(current, previous)
+		if got := e.Severities(); !reflect.DeepEqual(got, s.wantSeverities) {
+			t.Errorf("at %ds: Severities() = %v, want %v", s.sec, got, s.wantSeverities)
+		}
+		if got := e.ShouldExecuteScripts(alertTypes); got != s.wantScripts {
+			t.Errorf("at %ds: ShouldExecuteScripts(%v) = %v, want %v", s.sec, alertTypes, got, s.wantScripts)
+		}
+		previous = current
+	}
+
+	resolved := e.Resolved()
+	if len(resolved) != 1 || resolved[0].Severity != "page" {
+		t.Errorf("Resolved() = %+v, want cpu at page", resolved)
+	}
+}
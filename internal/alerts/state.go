@@ -0,0 +1,227 @@
+package alerts
+
+import (
+	"sort"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/rules"
+)
+
+// alertState tracks one alert type from the first sample its condition
+// held. An alert type without state is inactive; with a zero firedAt it is
+// pending, otherwise firing. When the condition clears, a firing type is
+// resolved and its state dropped. A firing type has the severity of the
+// highest tier it crossed on the last sample, previous the one it had on the
+// sample before (empty on the sample it fired), and highest the highest it
+// reached since it fired. notified is set once a script run included it.
+type alertState struct {
+	since    time.Time
+	firedAt  time.Time
+	severity string
+	previous string
+	highest  string
+	notified bool
+}
+
+// Resolution is a firing alert type whose condition cleared. Duration runs
+// from the sample it fired on to the sample it resolved on, and Severity is
+// the highest severity it reached. Notified is set when scripts ran for the
+// type while it fired, and Flapping when notifications for the type are
+// suppressed.
+type Resolution struct {
+	Type     string
+	Severity string
+	FiredAt  time.Time
+	Duration time.Duration
+	Notified bool
+	Flapping bool
+}
+
+// advance moves every alert type through its lifecycle given the types
+// whose rules match at now, the types matching with clear thresholds, which
+// keep an already firing type firing, and the severity tiers firing for
+// each type. A type with a firing tier fires at once, since the tier's own
+// for_sec has already passed. It returns the firing types.
+func (e *Engine) advance(now time.Time, matches, held []rules.Match, escalated map[string]string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// byType holds the rule behind each matched or held type; the others
+	// only fire through a tier.
+	byType := make(map[string]*config.Rule, len(matches))
+	var types []string
+	for _, m := range matches {
+		byType[m.Type] = m.Rule
+		types = append(types, m.Type)
+	}
+	for _, m := range held {
+		if state, ok := e.states[m.Type]; ok && !state.firedAt.IsZero() && byType[m.Type] == nil {
+			byType[m.Type] = m.Rule
+			types = append(types, m.Type)
+		}
+	}
+	active := make(map[string]bool, len(types))
+	for _, alertType := range types {
+		active[alertType] = true
+	}
+	matched := len(types)
+	for alertType := range escalated {
+		if !active[alertType] {
+			active[alertType] = true
+			types = append(types, alertType)
+		}
+	}
+	sort.Strings(types[matched:])
+	e.newPending = nil
+	e.resolved = nil
+
+	var firing []string
+	for _, alertType := range types {
+		rule := byType[alertType]
+		tier, escalating := escalated[alertType]
+
+		state, ok := e.states[alertType]
+		if !ok {
+			state = &alertState{since: now}
+			e.states[alertType] = state
+		}
+		if state.firedAt.IsZero() {
+			if !escalating && now.Sub(state.since) < time.Duration(rule.ForSec)*time.Second {
+				if !ok {
+					e.newPending = append(e.newPending, alertType)
+				}
+				continue
+			}
+			state.firedAt = now
+			e.recordChange(alertType, now)
+		}
+
+		state.previous = state.severity
+		state.severity = tier
+		if rule != nil && config.SeverityRank(rule.Severity) > config.SeverityRank(state.severity) {
+			state.severity = rule.Severity
+		}
+		if config.SeverityRank(state.severity) > config.SeverityRank(state.highest) {
+			state.highest = state.severity
+		}
+		firing = append(firing, alertType)
+	}
+
+	for alertType, state := range e.states {
+		if active[alertType] {
+			continue
+		}
+		if !state.firedAt.IsZero() {
+			e.resolved = append(e.resolved, Resolution{
+				Type:     alertType,
+				Severity: state.highest,
+				FiredAt:  state.firedAt,
+				Duration: now.Sub(state.firedAt),
+				Notified: state.notified,
+			})
+			e.recordChange(alertType, now)
+		}
+		delete(e.states, alertType)
+	}
+	sort.Slice(e.resolved, func(i, j int) bool {
+		return e.resolved[i].Type < e.resolved[j].Type
+	})
+
+	e.updateFlapping(now)
+	for i := range e.resolved {
+		e.resolved[i].Flapping = e.flapping[e.resolved[i].Type]
+	}
+
+	return firing
+}
+
+// recordChange notes that an alert type fired or resolved at now.
+func (e *Engine) recordChange(alertType string, now time.Time) {
+	if e.cfg.Alerts.Flapping.Enabled {
+		e.changes[alertType] = append(e.changes[alertType], now)
+	}
+}
+
+// updateFlapping drops changes older than the flapping window, marks types
+// with more than max_changes left as flapping, and clears the mark from
+// types without any.
+func (e *Engine) updateFlapping(now time.Time) {
+	e.flapStarted = nil
+	e.flapStopped = nil
+	if !e.cfg.Alerts.Flapping.Enabled {
+		return
+	}
+
+	cfg := e.cfg.Alerts.Flapping
+	window := time.Duration(cfg.WindowSec) * time.Second
+	for alertType, changes := range e.changes {
+		recent := changes[:0]
+		for _, t := range changes {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(e.changes, alertType)
+			continue
+		}
+		e.changes[alertType] = recent
+		if len(recent) > cfg.MaxChanges && !e.flapping[alertType] {
+			e.flapping[alertType] = true
+			e.flapStarted = append(e.flapStarted, alertType)
+		}
+	}
+	for alertType := range e.flapping {
+		if _, ok := e.changes[alertType]; !ok {
+			delete(e.flapping, alertType)
+			e.flapStopped = append(e.flapStopped, alertType)
+		}
+	}
+	sort.Strings(e.flapStarted)
+	sort.Strings(e.flapStopped)
+}
+
+// NewlyPending returns the alert types that started pending on the last
+// Detect call: their condition holds but has not yet lasted for_sec.
+func (e *Engine) NewlyPending() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.newPending
+}
+
+// Resolved returns the alert types that stopped firing on the last Detect
+// call.
+func (e *Engine) Resolved() []Resolution {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.resolved
+}
+
+// FlappingChanges returns the alert types that started and stopped
+// flapping on the last Detect call. While flapping, a type still fires and
+// resolves, but ShouldExecuteScripts ignores it and its resolutions are
+// marked Flapping.
+func (e *Engine) FlappingChanges() (started, stopped []string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.flapStarted, e.flapStopped
+}
+
+// Severities returns the severity of each alert type that fired on the
+// last Detect call: the highest tier whose thresholds it crossed.
+func (e *Engine) Severities() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	severities := make(map[string]string)
+	for alertType, state := range e.states {
+		if !state.firedAt.IsZero() {
+			severities[alertType] = state.severity
+		}
+	}
+	return severities
+}
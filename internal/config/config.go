@@ -2,7 +2,15 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"os/user"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -14,66 +22,594 @@ type Config struct {
 	LogDir                string            `yaml:"log_dir"`
 	RetentionDays         int               `yaml:"retention_days"`
 	Interface             string            `yaml:"interface"`
+	Interfaces            []string          `yaml:"interfaces"`
+	Disks                 []string          `yaml:"disks"`
+	Filesystems           Filesystems       `yaml:"filesystems"`
+	TopProcesses          TopProcesses      `yaml:"top_processes"`
+	Processes             []WatchedProcess  `yaml:"processes"`
+	Cgroups               Cgroups           `yaml:"cgroups"`
+	KernelLog             KernelLog         `yaml:"kernel_log"`
+	LogFiles              []LogFile         `yaml:"log_files"`
+	Thermal               Thermal           `yaml:"thermal"`
+	KernelTables          KernelTables      `yaml:"kernel_tables"`
+	Textfile              Textfile          `yaml:"textfile"`
+	Sources               Sources           `yaml:"sources"`
+	ExecPlugins           []ExecPlugin      `yaml:"exec_plugins"`
+	Probes                []Probe           `yaml:"probes"`
 	Spikes                Spikes            `yaml:"spikes"`
 	Alerts                Alerts            `yaml:"alerts"`
+	Rules                 []Rule            `yaml:"rules"`
 	Scripts               Scripts           `yaml:"scripts"`
 	Env                   map[string]string `yaml:"env"`
 }
 
+// SeverityLevels lists the alert severities from lowest to highest.
+var SeverityLevels = []string{"info", "warning", "critical", "page"}
+
+// SeverityRank returns the position of severity in SeverityLevels, or -1
+// for an unknown severity.
+func SeverityRank(severity string) int {
+	for i, level := range SeverityLevels {
+		if level == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Sources toggles the built-in metric sources. Every source is on unless
+// disabled here, except thermal and kernel_log, which follow their own
+// enabled settings when not set, and textfile, which is on when a
+// directory is configured.
+type Sources struct {
+	CPU          SourceConfig `yaml:"cpu"`
+	Load         SourceConfig `yaml:"load"`
+	KernelTables SourceConfig `yaml:"kernel_tables"`
+	Memory       SourceConfig `yaml:"memory"`
+	Network      SourceConfig `yaml:"network"`
+	Sockets      SourceConfig `yaml:"sockets"`
+	Pressure     SourceConfig `yaml:"pressure"`
+	Disk         SourceConfig `yaml:"disk"`
+	Filesystem   SourceConfig `yaml:"filesystem"`
+	Thermal      SourceConfig `yaml:"thermal"`
+	Cgroup       SourceConfig `yaml:"cgroup"`
+	Processes    SourceConfig `yaml:"processes"`
+	KernelLog    SourceConfig `yaml:"kernel_log"`
+	Textfile     SourceConfig `yaml:"textfile"`
+	LogFiles     SourceConfig `yaml:"log_files"`
+}
+
+// ExecPlugin is an external command run on its own interval whose output
+// is parsed into samples, either Prometheus text ("prometheus") or
+// key=value lines ("keyvalue").
+type ExecPlugin struct {
+	Name        string            `yaml:"name"`
+	Command     string            `yaml:"command"`
+	Args        []string          `yaml:"args"`
+	Format      string            `yaml:"format"`
+	IntervalSec int               `yaml:"interval_sec"`
+	TimeoutSec  int               `yaml:"timeout_sec"`
+	Env         map[string]string `yaml:"env"`
+}
+
+// Probe is an HTTP(S) check when URL is set and a TCP connect check when
+// Address (host:port) is set. An HTTP probe succeeds when the status code
+// is in ExpectStatus (any 2xx or 3xx when empty) and, if set, the body
+// matches BodyRegex.
+type Probe struct {
+	Name               string `yaml:"name"`
+	URL                string `yaml:"url"`
+	Method             string `yaml:"method"`
+	ExpectStatus       []int  `yaml:"expect_status"`
+	BodyRegex          string `yaml:"body_regex"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	Address            string `yaml:"address"`
+	IntervalSec        int    `yaml:"interval_sec"`
+	TimeoutSec         int    `yaml:"timeout_sec"`
+}
+
+// SampleRule fires when any sample named Metric, with at least the given
+// labels, compares true against Threshold using Op (>, >=, <, <=, ==, !=).
+type SampleRule struct {
+	Name      string            `yaml:"name"`
+	Metric    string            `yaml:"metric"`
+	Labels    map[string]string `yaml:"labels"`
+	Op        string            `yaml:"op"`
+	Threshold float64           `yaml:"threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+func (r SampleRule) Matches(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	default:
+		return value >= r.Threshold
+	}
+}
+
+// RuleSettings are the keys every spike and alert rule takes besides its
+// thresholds. ForSec and Severity only apply to alerts, as do the severity
+// tiers in Severities, which map a level to a block of the rule's own keys.
+// Clear is a block of the rule's own keys holding its clear thresholds.
+type RuleSettings struct {
+	ForSec     int                  `yaml:"for_sec"`
+	Severity   string               `yaml:"severity"`
+	Severities map[string]yaml.Node `yaml:"severities"`
+	Clear      yaml.Node            `yaml:"clear"`
+}
+
+func (s *RuleSettings) settings() *RuleSettings {
+	return s
+}
+
+type SourceConfig struct {
+	Enabled *bool `yaml:"enabled"`
+}
+
+func (s SourceConfig) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
 type Spikes struct {
-	CPU     CPUSpike     `yaml:"cpu"`
-	Memory  MemorySpike  `yaml:"memory"`
-	Network NetworkSpike `yaml:"network"`
+	CPU        CPUSpike        `yaml:"cpu"`
+	Memory     MemorySpike     `yaml:"memory"`
+	Network    NetworkSpike    `yaml:"network"`
+	Packets    PacketsSpike    `yaml:"packets"`
+	Sockets    SocketRules     `yaml:"sockets"`
+	Pressure   PressureRules   `yaml:"pressure"`
+	Load       LoadRules       `yaml:"load"`
+	Tables     TableRules      `yaml:"kernel_tables"`
+	Cgroup     CgroupRules     `yaml:"cgroup"`
+	Thermal    ThermalRules    `yaml:"thermal"`
+	Probes     ProbeRules      `yaml:"probes"`
+	LogFiles   LogRules        `yaml:"log_files"`
+	Disk       DiskSpike       `yaml:"disk"`
+	Filesystem FilesystemSpike `yaml:"filesystem"`
+	Custom     []SampleRule    `yaml:"custom"`
 }
 
 type Alerts struct {
-	CPU     CPUAlert     `yaml:"cpu"`
-	Memory  MemoryAlert  `yaml:"memory"`
-	Network NetworkAlert `yaml:"network"`
+	CPU        CPUAlert        `yaml:"cpu"`
+	Memory     MemoryAlert     `yaml:"memory"`
+	Network    NetworkAlert    `yaml:"network"`
+	Packets    PacketsAlert    `yaml:"packets"`
+	Link       LinkAlert       `yaml:"link"`
+	Sockets    SocketRules     `yaml:"sockets"`
+	OOMKill    OOMKillAlert    `yaml:"oom_kill"`
+	Pressure   PressureRules   `yaml:"pressure"`
+	Load       LoadRules       `yaml:"load"`
+	Tables     TableRules      `yaml:"kernel_tables"`
+	Cgroup     CgroupRules     `yaml:"cgroup"`
+	Thermal    ThermalRules    `yaml:"thermal"`
+	Probes     ProbeRules      `yaml:"probes"`
+	LogFiles   LogRules        `yaml:"log_files"`
+	Disk       DiskAlert       `yaml:"disk"`
+	Filesystem FilesystemAlert `yaml:"filesystem"`
+	Textfile   TextfileAlert   `yaml:"textfile"`
+	Custom     []SampleRule    `yaml:"custom"`
+	Flapping   FlappingAlert   `yaml:"flapping"`
 }
 
 type CPUSpike struct {
 	Enabled           bool    `yaml:"enabled"`
 	AbsoluteThreshold float64 `yaml:"absolute_threshold"`
 	RelativeThreshold float64 `yaml:"relative_threshold"`
+	CoreThreshold     float64 `yaml:"core_threshold"`
+	IOWaitThreshold   float64 `yaml:"iowait_threshold"`
+	StealThreshold    float64 `yaml:"steal_threshold"`
+	IncludeIOWait     bool    `yaml:"include_iowait"`
+
+	RuleSettings `yaml:",inline"`
 }
 
 type MemorySpike struct {
-	Enabled           bool    `yaml:"enabled"`
-	AbsoluteThreshold float64 `yaml:"absolute_threshold"`
-	RelativeThreshold float64 `yaml:"relative_threshold"`
+	Enabled                     bool    `yaml:"enabled"`
+	AbsoluteThreshold           float64 `yaml:"absolute_threshold"`
+	RelativeThreshold           float64 `yaml:"relative_threshold"`
+	SwapUsedThreshold           float64 `yaml:"swap_used_threshold"`
+	SwapInPagesPSThreshold      float64 `yaml:"swap_in_pages_ps_threshold"`
+	MajorFaultsPSThreshold      float64 `yaml:"major_faults_ps_threshold"`
+	DirtyGrowthBytesPSThreshold float64 `yaml:"dirty_growth_bytes_ps_threshold"`
+
+	RuleSettings `yaml:",inline"`
 }
 
 type NetworkSpike struct {
+	Enabled           bool                         `yaml:"enabled"`
+	RxMbpsThreshold   float64                      `yaml:"rx_mbps_threshold"`
+	TxMbpsThreshold   float64                      `yaml:"tx_mbps_threshold"`
+	RelativeThreshold float64                      `yaml:"relative_threshold"`
+	Interfaces        map[string]NetworkThresholds `yaml:"interfaces"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type PacketsSpike struct {
+	Enabled            bool                         `yaml:"enabled"`
+	PacketsPSThreshold float64                      `yaml:"packets_ps_threshold"`
+	ErrorsPSThreshold  float64                      `yaml:"errors_ps_threshold"`
+	DropsPSThreshold   float64                      `yaml:"drops_ps_threshold"`
+	Interfaces         map[string]PacketsThresholds `yaml:"interfaces"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type DiskSpike struct {
 	Enabled           bool    `yaml:"enabled"`
-	RxMbpsThreshold   float64 `yaml:"rx_mbps_threshold"`
-	TxMbpsThreshold   float64 `yaml:"tx_mbps_threshold"`
+	UtilThreshold     float64 `yaml:"util_threshold"`
+	AwaitMsThreshold  float64 `yaml:"await_ms_threshold"`
+	IOPSThreshold     float64 `yaml:"iops_threshold"`
 	RelativeThreshold float64 `yaml:"relative_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type FilesystemSpike struct {
+	Enabled             bool                            `yaml:"enabled"`
+	UsedThreshold       float64                         `yaml:"used_threshold"`
+	InodesUsedThreshold float64                         `yaml:"inodes_used_threshold"`
+	Mounts              map[string]FilesystemThresholds `yaml:"mounts"`
+
+	RuleSettings `yaml:",inline"`
 }
 
 type CPUAlert struct {
 	Enabled           bool    `yaml:"enabled"`
 	AbsoluteThreshold float64 `yaml:"absolute_threshold"`
 	RelativeThreshold float64 `yaml:"relative_threshold"`
+	CoreThreshold     float64 `yaml:"core_threshold"`
+	IOWaitThreshold   float64 `yaml:"iowait_threshold"`
+	StealThreshold    float64 `yaml:"steal_threshold"`
+	IncludeIOWait     bool    `yaml:"include_iowait"`
+
+	RuleSettings `yaml:",inline"`
 }
 
 type MemoryAlert struct {
-	Enabled           bool    `yaml:"enabled"`
-	AbsoluteThreshold float64 `yaml:"absolute_threshold"`
+	Enabled                     bool    `yaml:"enabled"`
+	AbsoluteThreshold           float64 `yaml:"absolute_threshold"`
+	SwapUsedThreshold           float64 `yaml:"swap_used_threshold"`
+	SwapInPagesPSThreshold      float64 `yaml:"swap_in_pages_ps_threshold"`
+	MajorFaultsPSThreshold      float64 `yaml:"major_faults_ps_threshold"`
+	DirtyGrowthBytesPSThreshold float64 `yaml:"dirty_growth_bytes_ps_threshold"`
+
+	RuleSettings `yaml:",inline"`
 }
 
 type NetworkAlert struct {
-	Enabled         bool    `yaml:"enabled"`
+	Enabled         bool                         `yaml:"enabled"`
+	RxMbpsThreshold float64                      `yaml:"rx_mbps_threshold"`
+	TxMbpsThreshold float64                      `yaml:"tx_mbps_threshold"`
+	Interfaces      map[string]NetworkThresholds `yaml:"interfaces"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+// NetworkThresholds overrides the network spike or alert thresholds for the
+// interfaces matching its key, a name or glob pattern.
+type NetworkThresholds struct {
 	RxMbpsThreshold float64 `yaml:"rx_mbps_threshold"`
 	TxMbpsThreshold float64 `yaml:"tx_mbps_threshold"`
 }
 
+type PacketsAlert struct {
+	Enabled            bool                         `yaml:"enabled"`
+	PacketsPSThreshold float64                      `yaml:"packets_ps_threshold"`
+	ErrorsPSThreshold  float64                      `yaml:"errors_ps_threshold"`
+	DropsPSThreshold   float64                      `yaml:"drops_ps_threshold"`
+	Interfaces         map[string]PacketsThresholds `yaml:"interfaces"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+// PacketsThresholds overrides the packet, error and drop rate thresholds for
+// the interfaces matching its key, a name or glob pattern.
+type PacketsThresholds struct {
+	PacketsPSThreshold float64 `yaml:"packets_ps_threshold"`
+	ErrorsPSThreshold  float64 `yaml:"errors_ps_threshold"`
+	DropsPSThreshold   float64 `yaml:"drops_ps_threshold"`
+}
+
+type LinkAlert struct {
+	Enabled      bool `yaml:"enabled"`
+	AlertOnDown  bool `yaml:"alert_on_down"`
+	AlertOnFlap  bool `yaml:"alert_on_flap"`
+	MinSpeedMbps int  `yaml:"min_speed_mbps"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type DiskAlert struct {
+	Enabled          bool    `yaml:"enabled"`
+	UtilThreshold    float64 `yaml:"util_threshold"`
+	AwaitMsThreshold float64 `yaml:"await_ms_threshold"`
+	IOPSThreshold    float64 `yaml:"iops_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type FilesystemAlert struct {
+	Enabled             bool                            `yaml:"enabled"`
+	UsedThreshold       float64                         `yaml:"used_threshold"`
+	InodesUsedThreshold float64                         `yaml:"inodes_used_threshold"`
+	Mounts              map[string]FilesystemThresholds `yaml:"mounts"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+// FilesystemThresholds overrides the filesystem spike or alert thresholds for
+// the mount points matching its key, a path or glob pattern.
+type FilesystemThresholds struct {
+	UsedThreshold       float64 `yaml:"used_threshold"`
+	InodesUsedThreshold float64 `yaml:"inodes_used_threshold"`
+}
+
+type Filesystems struct {
+	MountPoints    []string `yaml:"mount_points"`
+	IncludeFSTypes []string `yaml:"include_fstypes"`
+	ExcludeFSTypes []string `yaml:"exclude_fstypes"`
+}
+
+// PressureRules holds PSI thresholds for spikes or alerts. Thresholds are
+// percentages of wall time stalled, as reported by /proc/pressure.
+type PressureRules struct {
+	Enabled bool               `yaml:"enabled"`
+	CPU     PressureThresholds `yaml:"cpu"`
+	Memory  PressureThresholds `yaml:"memory"`
+	IO      PressureThresholds `yaml:"io"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type PressureThresholds struct {
+	SomeAvg10Threshold  float64 `yaml:"some_avg10_threshold"`
+	SomeAvg60Threshold  float64 `yaml:"some_avg60_threshold"`
+	SomeAvg300Threshold float64 `yaml:"some_avg300_threshold"`
+	FullAvg10Threshold  float64 `yaml:"full_avg10_threshold"`
+	FullAvg60Threshold  float64 `yaml:"full_avg60_threshold"`
+	FullAvg300Threshold float64 `yaml:"full_avg300_threshold"`
+}
+
+// LoadRules holds load average and scheduler thresholds for spikes or
+// alerts. Per-core load thresholds divide the load average by the core count.
+type LoadRules struct {
+	Enabled                    bool    `yaml:"enabled"`
+	Load1PerCoreThreshold      float64 `yaml:"load1_per_core_threshold"`
+	Load5PerCoreThreshold      float64 `yaml:"load5_per_core_threshold"`
+	ProcsBlockedThreshold      float64 `yaml:"procs_blocked_threshold"`
+	ContextSwitchesPSThreshold float64 `yaml:"context_switches_ps_threshold"`
+	InterruptsPSThreshold      float64 `yaml:"interrupts_ps_threshold"`
+	ForksPSThreshold           float64 `yaml:"forks_ps_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+// KernelTables controls optional kernel table collection. Scanning inotify
+// usage reads every open file descriptor on the host, so it is opt-in.
+type KernelTables struct {
+	ScanInotify bool `yaml:"scan_inotify"`
+}
+
+// TableRules holds kernel table thresholds as a percentage of each limit.
+type TableRules struct {
+	Enabled                   bool    `yaml:"enabled"`
+	FileHandlesThreshold      float64 `yaml:"file_handles_threshold"`
+	ConntrackThreshold        float64 `yaml:"conntrack_threshold"`
+	PIDsThreshold             float64 `yaml:"pids_threshold"`
+	InotifyWatchesThreshold   float64 `yaml:"inotify_watches_threshold"`
+	InotifyInstancesThreshold float64 `yaml:"inotify_instances_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+// SocketRules holds TCP/UDP spike or alert thresholds. Counts are sockets
+// in the given state; rates are per second.
+type SocketRules struct {
+	Enabled                    bool    `yaml:"enabled"`
+	EstablishedThreshold       float64 `yaml:"established_threshold"`
+	SynRecvThreshold           float64 `yaml:"syn_recv_threshold"`
+	TimeWaitThreshold          float64 `yaml:"time_wait_threshold"`
+	CloseWaitThreshold         float64 `yaml:"close_wait_threshold"`
+	OrphansThreshold           float64 `yaml:"orphans_threshold"`
+	RetransPercentThreshold    float64 `yaml:"retrans_percent_threshold"`
+	ListenOverflowsPSThreshold float64 `yaml:"listen_overflows_ps_threshold"`
+	ListenDropsPSThreshold     float64 `yaml:"listen_drops_ps_threshold"`
+	UDPRcvbufErrorsPSThreshold float64 `yaml:"udp_rcvbuf_errors_ps_threshold"`
+	UDPInErrorsPSThreshold     float64 `yaml:"udp_in_errors_ps_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type TopProcesses struct {
+	Enabled     bool `yaml:"enabled"`
+	Count       int  `yaml:"count"`
+	EverySample bool `yaml:"every_sample"`
+	WindowMs    int  `yaml:"window_ms"`
+}
+
+// WatchedProcess selects processes by name, command line, pidfile or user
+// (all given criteria must match) and holds the alert rules for them.
+// Resource thresholds apply to the sum over every matching process.
+type WatchedProcess struct {
+	Name             string  `yaml:"name"`
+	MatchName        string  `yaml:"match_name"`
+	CmdlineRegex     string  `yaml:"cmdline_regex"`
+	Pidfile          string  `yaml:"pidfile"`
+	User             string  `yaml:"user"`
+	MinCount         int     `yaml:"min_count"`
+	MaxCount         int     `yaml:"max_count"`
+	AlertOnRestart   bool    `yaml:"alert_on_restart"`
+	CPUThreshold     float64 `yaml:"cpu_threshold"`
+	RSSMBThreshold   float64 `yaml:"rss_mb_threshold"`
+	FDThreshold      int     `yaml:"fd_threshold"`
+	ThreadsThreshold int     `yaml:"threads_threshold"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type OOMKillAlert struct {
+	Enabled  bool   `yaml:"enabled"`
+	Severity string `yaml:"severity"`
+}
+
+// FlappingAlert marks an alert type as flapping once it fired or resolved
+// more than MaxChanges times within WindowSec, and clears the mark after a
+// full window without changes.
+type FlappingAlert struct {
+	Enabled    bool `yaml:"enabled"`
+	MaxChanges int  `yaml:"max_changes"`
+	WindowSec  int  `yaml:"window_sec"`
+}
+
+// KernelLog configures kernel log tailing. Each rule raises an alert when
+// its pattern matches a new kernel message.
+type KernelLog struct {
+	Enabled bool            `yaml:"enabled"`
+	Path    string          `yaml:"path"`
+	Rules   []KernelLogRule `yaml:"rules"`
+}
+
+type KernelLogRule struct {
+	Name     string `yaml:"name"`
+	Pattern  string `yaml:"pattern"`
+	Severity string `yaml:"severity"`
+}
+
+// LogFile is an application log followed across rotation and truncation.
+// Each rule counts the new lines matching its pattern; a critical rule
+// raises an alert on any match.
+type LogFile struct {
+	Name  string        `yaml:"name"`
+	Path  string        `yaml:"path"`
+	Rules []LogFileRule `yaml:"rules"`
+}
+
+type LogFileRule struct {
+	Name     string `yaml:"name"`
+	Pattern  string `yaml:"pattern"`
+	Critical bool   `yaml:"critical"`
+}
+
+// LogRules holds log match rate thresholds in matches per second, with
+// per-rule overrides keyed by "<file>/<rule>" or a glob pattern.
+type LogRules struct {
+	Enabled       bool                     `yaml:"enabled"`
+	RateThreshold float64                  `yaml:"rate_threshold"`
+	Rules         map[string]LogThresholds `yaml:"rules"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type LogThresholds struct {
+	RateThreshold float64 `yaml:"rate_threshold"`
+}
+
+type Thermal struct {
+	Enabled   bool   `yaml:"enabled"`
+	SysfsRoot string `yaml:"sysfs_root"`
+}
+
+// ThermalRules holds temperature spike or alert thresholds in degrees
+// Celsius. Per-sensor overrides are keyed by sensor name or glob pattern.
+type ThermalRules struct {
+	Enabled         bool                         `yaml:"enabled"`
+	TempThreshold   float64                      `yaml:"temp_threshold"`
+	CritMargin      float64                      `yaml:"crit_margin"`
+	AlertOnThrottle bool                         `yaml:"alert_on_throttle"`
+	Sensors         map[string]ThermalThresholds `yaml:"sensors"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type ThermalThresholds struct {
+	TempThreshold float64 `yaml:"temp_threshold"`
+	CritMargin    float64 `yaml:"crit_margin"`
+}
+
+// Textfile configures the textfile collector, which reads the *.prom files
+// other tools drop into Dir.
+type Textfile struct {
+	Dir string `yaml:"dir"`
+}
+
+// TextfileAlert fires for files not modified within MaxAgeSec and, with
+// AlertOnError, for files that cannot be parsed. Per-file overrides are
+// keyed by file name or glob pattern.
+type TextfileAlert struct {
+	Enabled      bool                          `yaml:"enabled"`
+	MaxAgeSec    int                           `yaml:"max_age_sec"`
+	AlertOnError bool                          `yaml:"alert_on_error"`
+	Files        map[string]TextfileThresholds `yaml:"files"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type TextfileThresholds struct {
+	MaxAgeSec    int  `yaml:"max_age_sec"`
+	AlertOnError bool `yaml:"alert_on_error"`
+}
+
+// ProbeRules holds probe spike or alert thresholds, with per-probe
+// overrides keyed by probe name or glob pattern. CertDaysThreshold fires when the TLS
+// certificate expires within that many days.
+type ProbeRules struct {
+	Enabled            bool                       `yaml:"enabled"`
+	AlertOnFailure     bool                       `yaml:"alert_on_failure"`
+	LatencyMsThreshold float64                    `yaml:"latency_ms_threshold"`
+	CertDaysThreshold  float64                    `yaml:"cert_days_threshold"`
+	Probes             map[string]ProbeThresholds `yaml:"probes"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type ProbeThresholds struct {
+	AlertOnFailure     bool    `yaml:"alert_on_failure"`
+	LatencyMsThreshold float64 `yaml:"latency_ms_threshold"`
+	CertDaysThreshold  float64 `yaml:"cert_days_threshold"`
+}
+
+type Cgroups struct {
+	Root    string   `yaml:"root"`
+	Include []string `yaml:"include"`
+}
+
+// CgroupRules holds cgroup spike or alert thresholds, with per-cgroup
+// overrides keyed by path relative to the cgroup root or glob pattern.
+type CgroupRules struct {
+	Enabled             bool                        `yaml:"enabled"`
+	CPUThreshold        float64                     `yaml:"cpu_threshold"`
+	MemoryUsedThreshold float64                     `yaml:"memory_used_threshold"`
+	PidsUsedThreshold   float64                     `yaml:"pids_used_threshold"`
+	AlertOnOOMKill      bool                        `yaml:"alert_on_oom_kill"`
+	Cgroups             map[string]CgroupThresholds `yaml:"cgroups"`
+
+	RuleSettings `yaml:",inline"`
+}
+
+type CgroupThresholds struct {
+	CPUThreshold        float64 `yaml:"cpu_threshold"`
+	MemoryUsedThreshold float64 `yaml:"memory_used_threshold"`
+	PidsUsedThreshold   float64 `yaml:"pids_used_threshold"`
+	AlertOnOOMKill      bool    `yaml:"alert_on_oom_kill"`
+}
+
 type Scripts struct {
-	Dir         string `yaml:"dir"`
-	EnvFile     string `yaml:"env_file"`
-	DebounceSec int    `yaml:"debounce_sec"`
-	TimeoutSec  int    `yaml:"timeout_sec"`
-	Enabled     bool   `yaml:"enabled"`
+	Dir           string `yaml:"dir"`
+	EnvFile       string `yaml:"env_file"`
+	ProcessesFile string `yaml:"processes_file"`
+	DebounceSec   int    `yaml:"debounce_sec"`
+	TimeoutSec    int    `yaml:"timeout_sec"`
+	Enabled       bool   `yaml:"enabled"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -99,6 +635,109 @@ func LoadConfig(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// ruleSection is a spike or alert section, or any other rule with
+// RuleSettings.
+type ruleSection interface {
+	settings() *RuleSettings
+}
+
+// ruleBlock is a spike or alert rule by its config path.
+type ruleBlock struct {
+	name  string
+	rules ruleSection
+}
+
+func (c *Config) ruleBlocks() []ruleBlock {
+	blocks := []ruleBlock{
+		{"spikes.cpu", &c.Spikes.CPU},
+		{"spikes.memory", &c.Spikes.Memory},
+		{"spikes.network", &c.Spikes.Network},
+		{"spikes.packets", &c.Spikes.Packets},
+		{"spikes.sockets", &c.Spikes.Sockets},
+		{"spikes.pressure", &c.Spikes.Pressure},
+		{"spikes.load", &c.Spikes.Load},
+		{"spikes.kernel_tables", &c.Spikes.Tables},
+		{"spikes.cgroup", &c.Spikes.Cgroup},
+		{"spikes.thermal", &c.Spikes.Thermal},
+		{"spikes.probes", &c.Spikes.Probes},
+		{"spikes.log_files", &c.Spikes.LogFiles},
+		{"spikes.disk", &c.Spikes.Disk},
+		{"spikes.filesystem", &c.Spikes.Filesystem},
+		{"alerts.cpu", &c.Alerts.CPU},
+		{"alerts.memory", &c.Alerts.Memory},
+		{"alerts.network", &c.Alerts.Network},
+		{"alerts.packets", &c.Alerts.Packets},
+		{"alerts.link", &c.Alerts.Link},
+		{"alerts.sockets", &c.Alerts.Sockets},
+		{"alerts.pressure", &c.Alerts.Pressure},
+		{"alerts.load", &c.Alerts.Load},
+		{"alerts.kernel_tables", &c.Alerts.Tables},
+		{"alerts.cgroup", &c.Alerts.Cgroup},
+		{"alerts.thermal", &c.Alerts.Thermal},
+		{"alerts.probes", &c.Alerts.Probes},
+		{"alerts.log_files", &c.Alerts.LogFiles},
+		{"alerts.disk", &c.Alerts.Disk},
+		{"alerts.filesystem", &c.Alerts.Filesystem},
+		{"alerts.textfile", &c.Alerts.Textfile},
+	}
+	for i := range c.Spikes.Custom {
+		blocks = append(blocks, ruleBlock{fmt.Sprintf("spikes.custom[%d]", i), &c.Spikes.Custom[i]})
+	}
+	for i := range c.Alerts.Custom {
+		blocks = append(blocks, ruleBlock{fmt.Sprintf("alerts.custom[%d]", i), &c.Alerts.Custom[i]})
+	}
+	for i := range c.Processes {
+		blocks = append(blocks, ruleBlock{fmt.Sprintf("processes[%d]", i), &c.Processes[i]})
+	}
+	for i := range c.Rules {
+		blocks = append(blocks, ruleBlock{fmt.Sprintf("rules[%d]", i), &c.Rules[i]})
+	}
+	return blocks
+}
+
+// overlay returns a copy of the rule that rules points to with node, a
+// block of the rule's own keys, decoded over it, or nil when node is unset.
+// The copy has maps of its own, so decoding never reaches the original.
+func overlay(name string, node yaml.Node, rules interface{}) (interface{}, error) {
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s must be a mapping", name)
+	}
+	v := reflect.New(reflect.TypeOf(rules).Elem())
+	v.Elem().Set(reflect.ValueOf(rules).Elem())
+	copyMaps(v.Elem())
+	if err := node.Decode(v.Interface()); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v.Interface(), nil
+}
+
+// copyMaps replaces every map in the struct v, and in the structs it
+// holds, with a copy.
+func copyMaps(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Map:
+			if f.IsNil() {
+				continue
+			}
+			m := reflect.MakeMapWithSize(f.Type(), f.Len())
+			for iter := f.MapRange(); iter.Next(); {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+			f.Set(m)
+		case reflect.Struct:
+			copyMaps(f)
+		}
+	}
+}
+
 func (c *Config) applyDefaults() {
 	if c.SampleIntervalSec <= 0 {
 		c.SampleIntervalSec = 1
@@ -112,21 +751,120 @@ func (c *Config) applyDefaults() {
 	if c.RetentionDays <= 0 {
 		c.RetentionDays = 30
 	}
-	if c.Interface == "" {
+	if c.Interface == "" && len(c.Interfaces) == 0 {
 		c.Interface = "eth0"
 	}
+	if c.Filesystems.ExcludeFSTypes == nil && len(c.Filesystems.IncludeFSTypes) == 0 {
+		c.Filesystems.ExcludeFSTypes = []string{
+			"proc", "sysfs", "devtmpfs", "devpts", "tmpfs", "cgroup", "cgroup2",
+			"securityfs", "pstore", "bpf", "debugfs", "tracefs", "mqueue",
+			"hugetlbfs", "configfs", "fusectl", "autofs", "binfmt_misc",
+			"overlay", "squashfs", "nsfs", "rpc_pipefs",
+			// Network filesystems can hang statfs when the server goes away.
+			"nfs", "nfs4", "cifs", "smb3", "smbfs", "9p", "ceph", "glusterfs",
+			"fuse.*",
+		}
+	}
+	if c.Cgroups.Root == "" {
+		c.Cgroups.Root = "/sys/fs/cgroup"
+	}
+	if c.Thermal.SysfsRoot == "" {
+		c.Thermal.SysfsRoot = "/sys"
+	}
+	if c.KernelLog.Path == "" {
+		c.KernelLog.Path = "/dev/kmsg"
+	}
+	if c.Sources.Thermal.Enabled == nil {
+		enabled := c.Thermal.Enabled
+		c.Sources.Thermal.Enabled = &enabled
+	}
+	if c.Sources.KernelLog.Enabled == nil {
+		enabled := c.KernelLog.Enabled
+		c.Sources.KernelLog.Enabled = &enabled
+	}
+	if c.Sources.Textfile.Enabled == nil {
+		enabled := c.Textfile.Dir != ""
+		c.Sources.Textfile.Enabled = &enabled
+	}
+	if c.TopProcesses.Count <= 0 {
+		c.TopProcesses.Count = 5
+	}
+	if c.TopProcesses.WindowMs <= 0 {
+		c.TopProcesses.WindowMs = 250
+	}
 	if c.Scripts.Dir == "" {
 		c.Scripts.Dir = "/etc/system-sentinel/sh"
 	}
 	if c.Scripts.EnvFile == "" {
 		c.Scripts.EnvFile = "/etc/system-sentinel/.env"
 	}
+	if c.Scripts.ProcessesFile == "" {
+		c.Scripts.ProcessesFile = "/etc/system-sentinel/processes.json"
+	}
 	if c.Scripts.DebounceSec <= 0 {
 		c.Scripts.DebounceSec = 60
 	}
 	if c.Scripts.TimeoutSec <= 0 {
 		c.Scripts.TimeoutSec = 30
 	}
+	for i := range c.ExecPlugins {
+		p := &c.ExecPlugins[i]
+		if p.Format == "" {
+			p.Format = "keyvalue"
+		}
+		if p.IntervalSec <= 0 {
+			p.IntervalSec = 60
+		}
+		if p.TimeoutSec <= 0 {
+			p.TimeoutSec = c.Scripts.TimeoutSec
+		}
+	}
+	if c.Alerts.Flapping.MaxChanges <= 0 {
+		c.Alerts.Flapping.MaxChanges = 4
+	}
+	if c.Alerts.Flapping.WindowSec <= 0 {
+		c.Alerts.Flapping.WindowSec = 600
+	}
+	for _, b := range c.ruleBlocks() {
+		if settings := b.rules.settings(); settings.Severity == "" {
+			settings.Severity = "critical"
+		}
+	}
+	if c.Alerts.OOMKill.Severity == "" {
+		c.Alerts.OOMKill.Severity = "critical"
+	}
+	for i := range c.KernelLog.Rules {
+		if c.KernelLog.Rules[i].Severity == "" {
+			c.KernelLog.Rules[i].Severity = "critical"
+		}
+	}
+	for i := range c.Probes {
+		p := &c.Probes[i]
+		if p.URL != "" && p.Method == "" {
+			p.Method = "GET"
+		}
+		if p.IntervalSec <= 0 {
+			p.IntervalSec = 30
+		}
+		if p.TimeoutSec <= 0 {
+			p.TimeoutSec = 5
+		}
+	}
+	for _, rules := range [][]SampleRule{c.Spikes.Custom, c.Alerts.Custom} {
+		for i := range rules {
+			if rules[i].Op == "" {
+				rules[i].Op = ">="
+			}
+		}
+	}
+	for i := range c.Rules {
+		if c.Rules[i].Op == "" {
+			c.Rules[i].Op = ">="
+		}
+		if c.Rules[i].Action == "" {
+			c.Rules[i].Action = "alert"
+		}
+	}
 }
 
 func (c *Config) validate() error {
@@ -139,9 +877,222 @@ func (c *Config) validate() error {
 	if c.RetentionDays <= 0 {
 		return fmt.Errorf("retention_days must be positive")
 	}
-	if c.Interface == "" {
+	if len(c.NetworkInterfaces()) == 0 {
 		return fmt.Errorf("interface cannot be empty")
 	}
+	for _, pattern := range c.NetworkInterfaces() {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %q: %w", pattern, err)
+		}
+	}
+	if c.TopProcesses.WindowMs >= c.SampleIntervalSec*1000 {
+		return fmt.Errorf("top_processes.window_ms must be shorter than sample_interval_sec")
+	}
+	for _, pattern := range append(append([]string{}, c.Filesystems.IncludeFSTypes...), c.Filesystems.ExcludeFSTypes...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid fstype pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range c.Cgroups.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid cgroup pattern %q: %w", pattern, err)
+		}
+	}
+	if c.Sources.Textfile.IsEnabled() && c.Textfile.Dir == "" {
+		return fmt.Errorf("textfile.dir is required when the textfile source is enabled")
+	}
+	for _, overrides := range []struct {
+		name string
+		keys []string
+	}{
+		{"spikes.network.interfaces", keysOf(c.Spikes.Network.Interfaces)},
+		{"spikes.packets.interfaces", keysOf(c.Spikes.Packets.Interfaces)},
+		{"spikes.cgroup.cgroups", keysOf(c.Spikes.Cgroup.Cgroups)},
+		{"spikes.thermal.sensors", keysOf(c.Spikes.Thermal.Sensors)},
+		{"spikes.log_files.rules", keysOf(c.Spikes.LogFiles.Rules)},
+		{"spikes.probes.probes", keysOf(c.Spikes.Probes.Probes)},
+		{"spikes.filesystem.mounts", keysOf(c.Spikes.Filesystem.Mounts)},
+		{"alerts.network.interfaces", keysOf(c.Alerts.Network.Interfaces)},
+		{"alerts.packets.interfaces", keysOf(c.Alerts.Packets.Interfaces)},
+		{"alerts.cgroup.cgroups", keysOf(c.Alerts.Cgroup.Cgroups)},
+		{"alerts.thermal.sensors", keysOf(c.Alerts.Thermal.Sensors)},
+		{"alerts.log_files.rules", keysOf(c.Alerts.LogFiles.Rules)},
+		{"alerts.probes.probes", keysOf(c.Alerts.Probes.Probes)},
+		{"alerts.filesystem.mounts", keysOf(c.Alerts.Filesystem.Mounts)},
+		{"alerts.textfile.files", keysOf(c.Alerts.Textfile.Files)},
+	} {
+		for _, pattern := range overrides.keys {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", overrides.name, pattern, err)
+			}
+		}
+	}
+	for _, b := range c.ruleBlocks() {
+		settings := b.rules.settings()
+		if SeverityRank(settings.Severity) < 0 {
+			return fmt.Errorf("%s.severity must be one of %s", b.name, strings.Join(SeverityLevels, ", "))
+		}
+		if _, err := overlay(b.name+".clear", settings.Clear, b.rules); err != nil {
+			return err
+		}
+		for level, node := range settings.Severities {
+			if SeverityRank(level) < 0 {
+				return fmt.Errorf("%s.severities: unknown severity %q", b.name, level)
+			}
+			if _, err := overlay(b.name+".severities."+level, node, b.rules); err != nil {
+				return err
+			}
+		}
+	}
+	if SeverityRank(c.Alerts.OOMKill.Severity) < 0 {
+		return fmt.Errorf("alerts.oom_kill.severity must be one of %s", strings.Join(SeverityLevels, ", "))
+	}
+	plugins := make(map[string]bool)
+	for i, p := range c.ExecPlugins {
+		if p.Name == "" {
+			return fmt.Errorf("exec_plugins[%d].name cannot be empty", i)
+		}
+		if plugins[p.Name] {
+			return fmt.Errorf("exec_plugins[%d]: duplicate name %q", i, p.Name)
+		}
+		plugins[p.Name] = true
+		if p.Command == "" {
+			return fmt.Errorf("exec_plugins[%d].command cannot be empty", i)
+		}
+		if p.Format != "prometheus" && p.Format != "keyvalue" {
+			return fmt.Errorf("exec_plugins[%d].format must be prometheus or keyvalue", i)
+		}
+	}
+	probes := make(map[string]bool)
+	for i, p := range c.Probes {
+		if p.Name == "" {
+			return fmt.Errorf("probes[%d].name cannot be empty", i)
+		}
+		if probes[p.Name] {
+			return fmt.Errorf("probes[%d]: duplicate name %q", i, p.Name)
+		}
+		probes[p.Name] = true
+		if (p.URL == "") == (p.Address == "") {
+			return fmt.Errorf("probes[%d]: exactly one of url and address is required", i)
+		}
+		if p.URL != "" {
+			u, err := url.Parse(p.URL)
+			if err != nil {
+				return fmt.Errorf("probes[%d].url: %w", i, err)
+			}
+			if u.Scheme != "http" && u.Scheme != "https" {
+				return fmt.Errorf("probes[%d].url must be http or https", i)
+			}
+		}
+		if p.Address != "" {
+			if _, _, err := net.SplitHostPort(p.Address); err != nil {
+				return fmt.Errorf("probes[%d].address: %w", i, err)
+			}
+		}
+		if _, err := regexp.Compile(p.BodyRegex); err != nil {
+			return fmt.Errorf("probes[%d].body_regex: %w", i, err)
+		}
+	}
+	for section, rules := range map[string][]SampleRule{"spikes": c.Spikes.Custom, "alerts": c.Alerts.Custom} {
+		for i, r := range rules {
+			if r.Name == "" || r.Metric == "" {
+				return fmt.Errorf("%s.custom[%d]: name and metric are required", section, i)
+			}
+			switch r.Op {
+			case ">", ">=", "<", "<=", "==", "!=":
+			default:
+				return fmt.Errorf("%s.custom[%d]: invalid op %q", section, i, r.Op)
+			}
+		}
+	}
+	declared := make(map[string]bool)
+	for i, r := range c.Rules {
+		if r.Name == "" || r.Metric == "" {
+			return fmt.Errorf("rules[%d]: name and metric are required", i)
+		}
+		if declared[r.Name] {
+			return fmt.Errorf("rules[%d]: duplicate name %q", i, r.Name)
+		}
+		declared[r.Name] = true
+		switch r.Op {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return fmt.Errorf("rules[%d]: invalid op %q", i, r.Op)
+		}
+		if r.Action != "log" && r.Action != "alert" {
+			return fmt.Errorf("rules[%d].action must be log or alert", i)
+		}
+	}
+	rules := make(map[string]bool)
+	for i, r := range c.KernelLog.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("kernel_log.rules[%d].name cannot be empty", i)
+		}
+		if rules[r.Name] {
+			return fmt.Errorf("kernel_log.rules[%d]: duplicate name %q", i, r.Name)
+		}
+		rules[r.Name] = true
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("kernel_log.rules[%d].pattern: %w", i, err)
+		}
+		if SeverityRank(r.Severity) < 0 {
+			return fmt.Errorf("kernel_log.rules[%d].severity must be one of %s", i, strings.Join(SeverityLevels, ", "))
+		}
+	}
+	logFiles := make(map[string]bool)
+	for i, f := range c.LogFiles {
+		if f.Name == "" || strings.Contains(f.Name, "/") {
+			return fmt.Errorf("log_files[%d].name must be set and cannot contain '/'", i)
+		}
+		if logFiles[f.Name] {
+			return fmt.Errorf("log_files[%d]: duplicate name %q", i, f.Name)
+		}
+		logFiles[f.Name] = true
+		if f.Path == "" {
+			return fmt.Errorf("log_files[%d].path cannot be empty", i)
+		}
+		if len(f.Rules) == 0 {
+			return fmt.Errorf("log_files[%d].rules cannot be empty", i)
+		}
+		fileRules := make(map[string]bool)
+		for j, r := range f.Rules {
+			if r.Name == "" {
+				return fmt.Errorf("log_files[%d].rules[%d].name cannot be empty", i, j)
+			}
+			if fileRules[r.Name] {
+				return fmt.Errorf("log_files[%d].rules[%d]: duplicate name %q", i, j, r.Name)
+			}
+			fileRules[r.Name] = true
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("log_files[%d].rules[%d].pattern: %w", i, j, err)
+			}
+		}
+	}
+	names := make(map[string]bool)
+	for i, p := range c.Processes {
+		if p.Name == "" {
+			return fmt.Errorf("processes[%d].name cannot be empty", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("processes[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+		if p.MatchName == "" && p.CmdlineRegex == "" && p.Pidfile == "" && p.User == "" {
+			return fmt.Errorf("processes[%d]: one of match_name, cmdline_regex, pidfile or user is required", i)
+		}
+		if p.CmdlineRegex != "" {
+			if _, err := regexp.Compile(p.CmdlineRegex); err != nil {
+				return fmt.Errorf("processes[%d].cmdline_regex: %w", i, err)
+			}
+		}
+		if p.User != "" {
+			if _, err := strconv.Atoi(p.User); err != nil {
+				if _, err := user.Lookup(p.User); err != nil {
+					return fmt.Errorf("processes[%d].user: %w", i, err)
+				}
+			}
+		}
+	}
 	if c.Scripts.DebounceSec <= 0 {
 		return fmt.Errorf("scripts.debounce_sec must be positive")
 	}
@@ -158,3 +1109,42 @@ func (c *Config) SampleInterval() time.Duration {
 func (c *Config) CollectionInterval() time.Duration {
 	return time.Duration(c.CollectionIntervalSec) * time.Second
 }
+
+// NetworkInterfaces returns the interface names and glob patterns to monitor.
+// The legacy single interface setting is kept alongside the interfaces list.
+func (c *Config) NetworkInterfaces() []string {
+	if c.Interface == "" {
+		return c.Interfaces
+	}
+	for _, iface := range c.Interfaces {
+		if iface == c.Interface {
+			return c.Interfaces
+		}
+	}
+	return append([]string{c.Interface}, c.Interfaces...)
+}
+
+// legacyInterface returns the single interface setting when it is the only
+// one, as in v1.0.0 configs, and "" once an interfaces list is set.
+func (c *Config) legacyInterface() string {
+	if len(c.Interfaces) > 0 {
+		return ""
+	}
+	return c.Interface
+}
+
+// OverrideFor returns the key among keys, names or glob patterns, that
+// applies to target: an exact name wins over glob patterns, and among
+// patterns the longest match is used. It returns "" when none matches.
+func OverrideFor(keys []string, target string) string {
+	best := ""
+	for _, key := range keys {
+		if key == target {
+			return key
+		}
+		if ok, _ := path.Match(key, target); ok && len(key) > len(best) {
+			best = key
+		}
+	}
+	return best
+}
@@ -0,0 +1,754 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a declarative threshold rule. It matches every series named
+// Metric, from metrics.SnapshotSamples, with at least the given labels,
+// whose value compares true against Threshold using Op. With Relative, the
+// percent change since the previous sample is compared instead, and series
+// without a positive previous value are skipped. Rules with action "alert"
+// raise "rule:<name>" alerts; rules with action "log" raise spikes.
+type Rule struct {
+	SampleRule `yaml:",inline"`
+	Relative   bool   `yaml:"relative"`
+	Action     string `yaml:"action"`
+
+	// Variant is empty for the rules as configured. SpikeRules and
+	// AlertRules follow them with their variants: ClearVariant for a rule
+	// with its clear block applied, and a severity level for a rule with
+	// that severity tier applied, which has the tier's for_sec and fires
+	// at the tier's level.
+	Variant string `yaml:"-"`
+
+	// The remaining fields are only set on the rules translated from the
+	// spikes and alerts sections. Such a rule raises Type, followed by ":"
+	// and the series' target when TargetLabels is set: the values of those
+	// labels joined by "/". With Overrides, the section's per-target keys,
+	// it only matches targets for which OverrideFor picks Override; an
+	// empty Override stands for the section defaults, which apply to
+	// targets no key matches. Each of Requires must also match the series
+	// it names with the same labels, which must exist.
+	Type         string       `yaml:"-"`
+	TargetLabels []string     `yaml:"-"`
+	Overrides    []string     `yaml:"-"`
+	Override     string       `yaml:"-"`
+	Requires     []SampleRule `yaml:"-"`
+
+	// setting is the path of the section key a translated rule checks,
+	// such as "mounts", "/var", "used_threshold".
+	setting []string
+}
+
+// ClearVariant is the Variant of rules with their clear block applied.
+const ClearVariant = "clear"
+
+// Variant returns the rules of the given variant.
+func Variant(rules []Rule, variant string) []Rule {
+	var selected []Rule
+	for _, r := range rules {
+		if r.Variant == variant {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// SpikeRules returns the rules evaluated for spikes: every enabled spike
+// section and the custom spike rules translated to rules, followed by the
+// declared rules with action log, each with its clear variants.
+func (c *Config) SpikeRules() []Rule {
+	s := c.Spikes
+
+	var rules []Rule
+	if s.CPU.Enabled {
+		rules = append(rules, translate("spikes.cpu", s.CPU, false, func(section string, cfg CPUSpike) []Rule {
+			return cpuRules(section, cfg.IncludeIOWait, cfg.AbsoluteThreshold, cfg.RelativeThreshold, cfg.CoreThreshold,
+				cfg.IOWaitThreshold, cfg.StealThreshold)
+		})...)
+	}
+	if s.Memory.Enabled {
+		rules = append(rules, translate("spikes.memory", s.Memory, false, func(section string, cfg MemorySpike) []Rule {
+			return memoryRules(section, cfg.AbsoluteThreshold, cfg.RelativeThreshold, cfg.SwapUsedThreshold,
+				cfg.SwapInPagesPSThreshold, cfg.MajorFaultsPSThreshold, cfg.DirtyGrowthBytesPSThreshold)
+		})...)
+	}
+	if s.Network.Enabled {
+		rules = append(rules, translate("spikes.network", s.Network, false, func(section string, cfg NetworkSpike) []Rule {
+			base := NetworkThresholds{RxMbpsThreshold: cfg.RxMbpsThreshold, TxMbpsThreshold: cfg.TxMbpsThreshold}
+			return networkRules(section, c.legacyInterface(), base, cfg.Interfaces, cfg.RelativeThreshold)
+		})...)
+	}
+	if s.Load.Enabled {
+		rules = append(rules, translate("spikes.load", s.Load, false, loadRules)...)
+	}
+	if s.Tables.Enabled {
+		rules = append(rules, translate("spikes.kernel_tables", s.Tables, false, tableRules)...)
+	}
+	if s.Packets.Enabled {
+		rules = append(rules, translate("spikes.packets", s.Packets, false, func(section string, cfg PacketsSpike) []Rule {
+			base := PacketsThresholds{PacketsPSThreshold: cfg.PacketsPSThreshold, ErrorsPSThreshold: cfg.ErrorsPSThreshold, DropsPSThreshold: cfg.DropsPSThreshold}
+			return packetsRules(section, base, cfg.Interfaces)
+		})...)
+	}
+	if s.Sockets.Enabled {
+		rules = append(rules, translate("spikes.sockets", s.Sockets, false, socketRules)...)
+	}
+	if s.Pressure.Enabled {
+		rules = append(rules, translate("spikes.pressure", s.Pressure, false, pressureRules)...)
+	}
+	if s.Cgroup.Enabled {
+		rules = append(rules, translate("spikes.cgroup", s.Cgroup, false, cgroupRules)...)
+	}
+	if s.Thermal.Enabled {
+		rules = append(rules, translate("spikes.thermal", s.Thermal, false, thermalRules)...)
+	}
+	if s.LogFiles.Enabled {
+		rules = append(rules, translate("spikes.log_files", s.LogFiles, false, logRules)...)
+	}
+	if s.Probes.Enabled {
+		rules = append(rules, translate("spikes.probes", s.Probes, false, probeRules)...)
+	}
+	if s.Disk.Enabled {
+		rules = append(rules, translate("spikes.disk", s.Disk, false, func(section string, cfg DiskSpike) []Rule {
+			return diskRules(section, cfg.UtilThreshold, cfg.AwaitMsThreshold, cfg.IOPSThreshold, cfg.RelativeThreshold)
+		})...)
+	}
+	if s.Filesystem.Enabled {
+		rules = append(rules, translate("spikes.filesystem", s.Filesystem, false, func(section string, cfg FilesystemSpike) []Rule {
+			base := FilesystemThresholds{UsedThreshold: cfg.UsedThreshold, InodesUsedThreshold: cfg.InodesUsedThreshold}
+			return filesystemRules(section, base, cfg.Mounts)
+		})...)
+	}
+	for i, r := range s.Custom {
+		rules = append(rules, translate(fmt.Sprintf("spikes.custom[%d]", i), r, false, customRules)...)
+	}
+
+	return append(rules, c.declaredRules("log")...)
+}
+
+// AlertRules returns the rules evaluated for alerts: every enabled alert
+// section, the kernel_log rules, the processes and the custom alert rules
+// translated to rules, followed by the declared rules with action alert,
+// each with its clear and severity tier variants.
+func (c *Config) AlertRules() []Rule {
+	a := c.Alerts
+
+	var rules []Rule
+	if a.CPU.Enabled {
+		rules = append(rules, translate("alerts.cpu", a.CPU, true, func(section string, cfg CPUAlert) []Rule {
+			return cpuRules(section, cfg.IncludeIOWait, cfg.AbsoluteThreshold, cfg.RelativeThreshold, cfg.CoreThreshold,
+				cfg.IOWaitThreshold, cfg.StealThreshold)
+		})...)
+	}
+	if a.Memory.Enabled {
+		rules = append(rules, translate("alerts.memory", a.Memory, true, func(section string, cfg MemoryAlert) []Rule {
+			return memoryRules(section, cfg.AbsoluteThreshold, 0, cfg.SwapUsedThreshold,
+				cfg.SwapInPagesPSThreshold, cfg.MajorFaultsPSThreshold, cfg.DirtyGrowthBytesPSThreshold)
+		})...)
+	}
+	if a.Network.Enabled {
+		rules = append(rules, translate("alerts.network", a.Network, true, func(section string, cfg NetworkAlert) []Rule {
+			base := NetworkThresholds{RxMbpsThreshold: cfg.RxMbpsThreshold, TxMbpsThreshold: cfg.TxMbpsThreshold}
+			return networkRules(section, c.legacyInterface(), base, cfg.Interfaces, 0)
+		})...)
+	}
+	if a.Load.Enabled {
+		rules = append(rules, translate("alerts.load", a.Load, true, loadRules)...)
+	}
+	if a.Tables.Enabled {
+		rules = append(rules, translate("alerts.kernel_tables", a.Tables, true, tableRules)...)
+	}
+	if a.Packets.Enabled {
+		rules = append(rules, translate("alerts.packets", a.Packets, true, func(section string, cfg PacketsAlert) []Rule {
+			base := PacketsThresholds{PacketsPSThreshold: cfg.PacketsPSThreshold, ErrorsPSThreshold: cfg.ErrorsPSThreshold, DropsPSThreshold: cfg.DropsPSThreshold}
+			return packetsRules(section, base, cfg.Interfaces)
+		})...)
+	}
+	if a.Link.Enabled {
+		rules = append(rules, translate("alerts.link", a.Link, true, linkRules)...)
+	}
+	if a.Sockets.Enabled {
+		rules = append(rules, translate("alerts.sockets", a.Sockets, true, socketRules)...)
+	}
+	if a.Pressure.Enabled {
+		rules = append(rules, translate("alerts.pressure", a.Pressure, true, pressureRules)...)
+	}
+	if a.OOMKill.Enabled {
+		for _, r := range checkRules("alerts.oom_kill",
+			check{key: "enabled", alertType: "oom_kill", metric: "oom_kills", op: ">", on: true},
+			check{key: "enabled", alertType: "oom_kill", metric: "oom_victims", op: ">", on: true},
+		) {
+			r.Severity = a.OOMKill.Severity
+			rules = append(rules, r)
+		}
+	}
+	for _, kr := range c.KernelLog.Rules {
+		rules = append(rules, Rule{
+			SampleRule: SampleRule{
+				Name:         "kernel_log.rules",
+				Metric:       "kernel_events",
+				Labels:       map[string]string{"rule": kr.Name},
+				Op:           ">",
+				RuleSettings: RuleSettings{Severity: kr.Severity},
+			},
+			Type:         "kernel",
+			TargetLabels: []string{"rule"},
+		})
+	}
+	for i, p := range c.Processes {
+		rules = append(rules, translate(fmt.Sprintf("processes[%d]", i), p, true, processRules)...)
+	}
+	if a.Cgroup.Enabled {
+		rules = append(rules, translate("alerts.cgroup", a.Cgroup, true, cgroupRules)...)
+	}
+	if a.Thermal.Enabled {
+		rules = append(rules, translate("alerts.thermal", a.Thermal, true, thermalRules)...)
+	}
+	if a.LogFiles.Enabled {
+		rules = append(rules, translate("alerts.log_files", a.LogFiles, true, func(section string, cfg LogRules) []Rule {
+			// Critical rules fire on any match, whatever the thresholds.
+			critical := targetRules("log_files", "", "log", []string{"file", "rule"}, nil, func(string) []check {
+				return []check{{key: "critical", metric: "log_matches", op: ">", on: true,
+					requires: []SampleRule{{Metric: "log_critical", Op: "==", Threshold: 1}}}}
+			})
+			return append(logRules(section, cfg), critical...)
+		})...)
+	}
+	if a.Probes.Enabled {
+		rules = append(rules, translate("alerts.probes", a.Probes, true, probeRules)...)
+	}
+	if a.Disk.Enabled {
+		rules = append(rules, translate("alerts.disk", a.Disk, true, func(section string, cfg DiskAlert) []Rule {
+			return diskRules(section, cfg.UtilThreshold, cfg.AwaitMsThreshold, cfg.IOPSThreshold, 0)
+		})...)
+	}
+	if a.Filesystem.Enabled {
+		rules = append(rules, translate("alerts.filesystem", a.Filesystem, true, func(section string, cfg FilesystemAlert) []Rule {
+			base := FilesystemThresholds{UsedThreshold: cfg.UsedThreshold, InodesUsedThreshold: cfg.InodesUsedThreshold}
+			return filesystemRules(section, base, cfg.Mounts)
+		})...)
+	}
+	if a.Textfile.Enabled {
+		rules = append(rules, translate("alerts.textfile", a.Textfile, true, textfileRules)...)
+	}
+	for i, r := range a.Custom {
+		rules = append(rules, translate(fmt.Sprintf("alerts.custom[%d]", i), r, true, customRules)...)
+	}
+
+	return append(rules, c.declaredRules("alert")...)
+}
+
+// translate translates one spikes or alerts section, or any other rule
+// with RuleSettings, and follows its rules with their clear variants and,
+// with tiers, their severity tier variants. A tier only covers the
+// settings its block sets, so the section's other rules do not escalate.
+// Every rule carries the for_sec and severity of the block it was
+// translated from. Blocks that do not decode, which LoadConfig rejects,
+// are skipped.
+func translate[T any, P interface {
+	*T
+	ruleSection
+}](name string, s T, tiers bool, toRules func(section string, s T) []Rule) []Rule {
+	settings := P(&s).settings()
+	rules := variant(toRules(name, s), "", settings.ForSec, settings.Severity)
+	if v, _ := overlay(name+".clear", settings.Clear, &s); v != nil {
+		rules = append(rules, variant(toRules(name, *v.(*T)), ClearVariant, settings.ForSec, settings.Severity)...)
+	}
+	if !tiers {
+		return rules
+	}
+	for _, level := range SeverityLevels {
+		node, ok := settings.Severities[level]
+		if !ok {
+			continue
+		}
+		v, _ := overlay(name+".severities."+level, node, &s)
+		if v == nil {
+			continue
+		}
+		for _, r := range variant(toRules(name, *v.(*T)), level, P(v.(*T)).settings().ForSec, level) {
+			if sets(&node, r.setting) {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+// sets reports whether node, a block of a section's own keys, sets the key
+// at path. Rules without a path, such as declared rules, are set by any
+// block.
+func sets(node *yaml.Node, path []string) bool {
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return false
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+			}
+		}
+		if value == nil {
+			return false
+		}
+		node = value
+	}
+	return true
+}
+
+// variant marks rules as the given variant with for_sec and severity.
+func variant(rules []Rule, name string, forSec int, severity string) []Rule {
+	for i := range rules {
+		rules[i].Variant = name
+		rules[i].ForSec = forSec
+		rules[i].Severity = severity
+	}
+	return rules
+}
+
+// customRules translates a custom spike or alert rule, raising
+// custom:<name>.
+func customRules(_ string, r SampleRule) []Rule {
+	return []Rule{{SampleRule: r, Type: "custom:" + r.Name}}
+}
+
+func (c *Config) declaredRules(action string) []Rule {
+	var rules []Rule
+	for i, r := range c.Rules {
+		if r.Action == action {
+			rules = append(rules, translate(fmt.Sprintf("rules[%d]", i), r, action == "alert", func(_ string, r Rule) []Rule {
+				return []Rule{r}
+			})...)
+		}
+	}
+	return rules
+}
+
+// check is one setting of a section: when on, it matches the series named
+// metric that compare true against threshold using op, and raises
+// alertType. key is the setting's config key, which names the rule.
+type check struct {
+	key       string
+	alertType string
+	metric    string
+	op        string
+	threshold float64
+	on        bool
+	requires  []SampleRule
+}
+
+// atLeast returns the check for the usual threshold setting, which fires at
+// or above threshold and is off at 0.
+func atLeast(key, alertType, metric string, threshold float64) check {
+	return check{key: key, alertType: alertType, metric: metric, op: ">=", threshold: threshold, on: threshold > 0}
+}
+
+// checkRules translates the checks of a section that are on.
+func checkRules(section string, checks ...check) []Rule {
+	var rules []Rule
+	for _, c := range checks {
+		if !c.on {
+			continue
+		}
+		rules = append(rules, Rule{
+			SampleRule: SampleRule{Name: section + "." + c.key, Metric: c.metric, Op: c.op, Threshold: c.threshold},
+			Type:       c.alertType,
+			Requires:   c.requires,
+			setting:    []string{c.key},
+		})
+	}
+	return rules
+}
+
+// targetRules translates a section checked per target, such as each
+// interface or mount, raising alertType followed by the target. Overrides,
+// keyed by name or glob pattern under overridesKey and picked by
+// OverrideFor, replace the section thresholds as a whole. checks returns
+// the checks of the override under key, or of the section itself when key
+// is "".
+func targetRules(section, overridesKey, alertType string, labels, keys []string, checks func(key string) []check) []Rule {
+	sort.Strings(keys)
+
+	var rules []Rule
+	for _, key := range append([]string{""}, keys...) {
+		prefix := section
+		if key != "" {
+			prefix = section + "." + overridesKey + "." + key
+		}
+		for _, r := range checkRules(prefix, checks(key)...) {
+			r.Type = alertType
+			r.TargetLabels = labels
+			r.Overrides = keys
+			r.Override = key
+			if key != "" {
+				r.setting = append([]string{overridesKey, key}, r.setting...)
+			}
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func keysOf[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// thresholdRule returns the rule for a section's key raising alertType
+// when metric is at or above threshold.
+func thresholdRule(section, key, metric string, threshold float64, alertType string) Rule {
+	return Rule{
+		SampleRule: SampleRule{Name: section + "." + key, Metric: metric, Op: ">=", Threshold: threshold},
+		Type:       alertType,
+		setting:    []string{key},
+	}
+}
+
+// relativeRule returns the rule for a section's key raising alertType when
+// metric rose by at least threshold percent since the previous sample.
+func relativeRule(section, key, metric string, threshold float64, alertType string) Rule {
+	r := thresholdRule(section, key, metric, threshold, alertType)
+	r.Relative = true
+	return r
+}
+
+// cpuRules translates a cpu section. The absolute threshold has always
+// applied even when 0, unlike the others. Overall usage counts iowait as
+// idle unless includeIOWait is set.
+func cpuRules(section string, includeIOWait bool, absolute, relative, core, iowait, steal float64) []Rule {
+	usage := "cpu_usage_percent"
+	if includeIOWait {
+		usage = "cpu_usage_incl_iowait_percent"
+	}
+	rules := []Rule{thresholdRule(section, "absolute_threshold", usage, absolute, "cpu")}
+	if relative > 0 {
+		rules = append(rules, relativeRule(section, "relative_threshold", usage, relative, "cpu"))
+	}
+	if core > 0 {
+		r := thresholdRule(section, "core_threshold", "cpu_core_usage_percent", core, "cpu_core")
+		r.TargetLabels = []string{"core"}
+		rules = append(rules, r)
+	}
+	return append(rules, checkRules(section,
+		atLeast("iowait_threshold", "cpu_iowait", "cpu_iowait_percent", iowait),
+		atLeast("steal_threshold", "cpu_steal", "cpu_steal_percent", steal),
+	)...)
+}
+
+// memoryRules translates a memory section. As for cpu, the absolute
+// threshold always applies.
+func memoryRules(section string, absolute, relative, swapUsed, swapIn, majorFaults, dirtyGrowth float64) []Rule {
+	rules := []Rule{thresholdRule(section, "absolute_threshold", "mem_used_percent", absolute, "memory")}
+	if relative > 0 {
+		rules = append(rules, relativeRule(section, "relative_threshold", "mem_used_percent", relative, "memory"))
+	}
+	return append(rules, checkRules(section,
+		atLeast("swap_used_threshold", "swap_used", "swap_used_percent", swapUsed),
+		atLeast("swap_in_pages_ps_threshold", "swap_in", "swap_in_pages_ps", swapIn),
+		atLeast("major_faults_ps_threshold", "major_faults", "major_faults_ps", majorFaults),
+		atLeast("dirty_growth_bytes_ps_threshold", "dirty_growth", "dirty_growth_bytes_ps", dirtyGrowth),
+	)...)
+}
+
+// networkRules translates a network section. The relative threshold applies
+// to every interface, whatever its overrides. A config watching only the
+// legacy single interface keeps the v1.0.0 rules, see legacyNetworkRules.
+func networkRules(section, legacy string, base NetworkThresholds, interfaces map[string]NetworkThresholds, relative float64) []Rule {
+	if legacy != "" {
+		return legacyNetworkRules(section, legacy, base, interfaces, relative)
+	}
+	rules := targetRules(section, "interfaces", "network", []string{"interface"}, keysOf(interfaces), func(key string) []check {
+		t := base
+		if key != "" {
+			t = interfaces[key]
+		}
+		return []check{
+			atLeast("rx_mbps_threshold", "", "net_rx_mbps", t.RxMbpsThreshold),
+			atLeast("tx_mbps_threshold", "", "net_tx_mbps", t.TxMbpsThreshold),
+		}
+	})
+	if relative > 0 {
+		for _, metric := range []string{"net_rx_mbps", "net_tx_mbps"} {
+			r := relativeRule(section, "relative_threshold", metric, relative, "network")
+			r.TargetLabels = []string{"interface"}
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// legacyNetworkRules translates a network section for a config that only
+// sets the single interface, as v1.0.0 configs did. Its checks raise network
+// rather than network:<iface>, and a threshold of 0 still fires on every
+// sample, so those configs and the scripts reading their alerts keep
+// working. An override matching the interface replaces the section
+// thresholds as usual.
+func legacyNetworkRules(section, iface string, base NetworkThresholds, interfaces map[string]NetworkThresholds, relative float64) []Rule {
+	t, prefix, setting := base, section, []string(nil)
+	if key := OverrideFor(keysOf(interfaces), iface); key != "" {
+		t = interfaces[key]
+		prefix = section + ".interfaces." + key
+		setting = []string{"interfaces", key}
+	}
+
+	rules := checkRules(prefix,
+		check{key: "rx_mbps_threshold", alertType: "network", metric: "net_rx_mbps", op: ">=", threshold: t.RxMbpsThreshold, on: true},
+		check{key: "tx_mbps_threshold", alertType: "network", metric: "net_tx_mbps", op: ">=", threshold: t.TxMbpsThreshold, on: true},
+	)
+	for i := range rules {
+		rules[i].setting = append(append([]string(nil), setting...), rules[i].setting...)
+	}
+	if relative > 0 {
+		for _, metric := range []string{"net_rx_mbps", "net_tx_mbps"} {
+			rules = append(rules, relativeRule(section, "relative_threshold", metric, relative, "network"))
+		}
+	}
+
+	labels := map[string]string{"interface": iface}
+	for i := range rules {
+		rules[i].Labels = labels
+	}
+	return rules
+}
+
+// packetsRules translates a packets section. Its rates are RX plus TX.
+func packetsRules(section string, base PacketsThresholds, interfaces map[string]PacketsThresholds) []Rule {
+	return targetRules(section, "interfaces", "packets", []string{"interface"}, keysOf(interfaces), func(key string) []check {
+		t := base
+		if key != "" {
+			t = interfaces[key]
+		}
+		return []check{
+			atLeast("packets_ps_threshold", "", "net_packets_ps", t.PacketsPSThreshold),
+			atLeast("errors_ps_threshold", "", "net_errors_ps", t.ErrorsPSThreshold),
+			atLeast("drops_ps_threshold", "", "net_drops_ps", t.DropsPSThreshold),
+		}
+	})
+}
+
+// linkRules translates the link section. Interfaces that are
+// administratively down are ignored, as is a speed the driver does not
+// report.
+func linkRules(section string, cfg LinkAlert) []Rule {
+	adminUp := SampleRule{Metric: "net_admin_up", Op: "==", Threshold: 1}
+	return targetRules(section, "", "link", []string{"interface"}, nil, func(string) []check {
+		return []check{
+			{key: "alert_on_down", metric: "net_link_up", op: "==", threshold: 0, on: cfg.AlertOnDown,
+				requires: []SampleRule{adminUp}},
+			{key: "alert_on_flap", metric: "net_carrier_changes", op: ">", threshold: 0, on: cfg.AlertOnFlap,
+				requires: []SampleRule{adminUp}},
+			{key: "min_speed_mbps", metric: "net_speed_mbps", op: "<", threshold: float64(cfg.MinSpeedMbps), on: cfg.MinSpeedMbps > 0,
+				requires: []SampleRule{adminUp, {Metric: "net_speed_mbps", Op: ">", Threshold: 0}}},
+		}
+	})
+}
+
+// loadRules translates a load section. Either per-core load average raises
+// load; the scheduler counters raise their own types.
+func loadRules(section string, cfg LoadRules) []Rule {
+	return checkRules(section,
+		atLeast("load1_per_core_threshold", "load", "load1_per_core", cfg.Load1PerCoreThreshold),
+		atLeast("load5_per_core_threshold", "load", "load5_per_core", cfg.Load5PerCoreThreshold),
+		atLeast("procs_blocked_threshold", "procs_blocked", "procs_blocked", cfg.ProcsBlockedThreshold),
+		atLeast("context_switches_ps_threshold", "context_switches", "context_switches_ps", cfg.ContextSwitchesPSThreshold),
+		atLeast("interrupts_ps_threshold", "interrupts", "interrupts_ps", cfg.InterruptsPSThreshold),
+		atLeast("forks_ps_threshold", "forks", "forks_ps", cfg.ForksPSThreshold),
+	)
+}
+
+// tableRules translates a kernel_tables section. A table whose limit cannot
+// be read is at 0% and never fires.
+func tableRules(section string, cfg TableRules) []Rule {
+	return checkRules(section,
+		atLeast("file_handles_threshold", "file_handles", "file_handles_percent", cfg.FileHandlesThreshold),
+		atLeast("conntrack_threshold", "conntrack", "conntrack_percent", cfg.ConntrackThreshold),
+		atLeast("pids_threshold", "pids", "pids_percent", cfg.PIDsThreshold),
+		atLeast("inotify_watches_threshold", "inotify_watches", "inotify_watches_percent", cfg.InotifyWatchesThreshold),
+		atLeast("inotify_instances_threshold", "inotify_instances", "inotify_instances_percent", cfg.InotifyInstancesThreshold),
+	)
+}
+
+func socketRules(section string, cfg SocketRules) []Rule {
+	return checkRules(section,
+		atLeast("established_threshold", "tcp_established", "tcp_established", cfg.EstablishedThreshold),
+		atLeast("syn_recv_threshold", "tcp_syn_recv", "tcp_syn_recv", cfg.SynRecvThreshold),
+		atLeast("time_wait_threshold", "tcp_time_wait", "tcp_time_wait", cfg.TimeWaitThreshold),
+		atLeast("close_wait_threshold", "tcp_close_wait", "tcp_close_wait", cfg.CloseWaitThreshold),
+		atLeast("orphans_threshold", "tcp_orphans", "tcp_orphans", cfg.OrphansThreshold),
+		atLeast("retrans_percent_threshold", "tcp_retrans", "tcp_retrans_percent", cfg.RetransPercentThreshold),
+		atLeast("listen_overflows_ps_threshold", "listen_overflows", "listen_overflows_ps", cfg.ListenOverflowsPSThreshold),
+		atLeast("listen_drops_ps_threshold", "listen_drops", "listen_drops_ps", cfg.ListenDropsPSThreshold),
+		atLeast("udp_rcvbuf_errors_ps_threshold", "udp_rcvbuf_errors", "udp_rcvbuf_errors_ps", cfg.UDPRcvbufErrorsPSThreshold),
+		atLeast("udp_in_errors_ps_threshold", "udp_in_errors", "udp_in_errors_ps", cfg.UDPInErrorsPSThreshold),
+	)
+}
+
+// pressureRules translates a pressure section, raising pressure:<resource>
+// for each resource with a threshold crossed.
+func pressureRules(section string, cfg PressureRules) []Rule {
+	var rules []Rule
+	for _, res := range []struct {
+		name string
+		t    PressureThresholds
+	}{{"cpu", cfg.CPU}, {"memory", cfg.Memory}, {"io", cfg.IO}} {
+		for _, r := range checkRules(section+"."+res.name,
+			atLeast("some_avg10_threshold", "pressure", "pressure_some_avg10", res.t.SomeAvg10Threshold),
+			atLeast("some_avg60_threshold", "pressure", "pressure_some_avg60", res.t.SomeAvg60Threshold),
+			atLeast("some_avg300_threshold", "pressure", "pressure_some_avg300", res.t.SomeAvg300Threshold),
+			atLeast("full_avg10_threshold", "pressure", "pressure_full_avg10", res.t.FullAvg10Threshold),
+			atLeast("full_avg60_threshold", "pressure", "pressure_full_avg60", res.t.FullAvg60Threshold),
+			atLeast("full_avg300_threshold", "pressure", "pressure_full_avg300", res.t.FullAvg300Threshold),
+		) {
+			r.Labels = map[string]string{"resource": res.name}
+			r.TargetLabels = []string{"resource"}
+			r.setting = append([]string{res.name}, r.setting...)
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// processRules translates a processes entry. Its resource thresholds apply
+// to the sums over every matching process.
+func processRules(_ string, p WatchedProcess) []Rule {
+	var rules []Rule
+	for _, r := range checkRules("processes."+p.Name,
+		check{key: "min_count", metric: "process_count", op: "<", threshold: float64(p.MinCount), on: p.MinCount > 0},
+		check{key: "max_count", metric: "process_count", op: ">", threshold: float64(p.MaxCount), on: p.MaxCount > 0},
+		check{key: "alert_on_restart", metric: "process_restarted", op: "==", threshold: 1, on: p.AlertOnRestart},
+		atLeast("cpu_threshold", "", "process_cpu_percent", p.CPUThreshold),
+		atLeast("rss_mb_threshold", "", "process_rss_bytes", p.RSSMBThreshold*1024*1024),
+		atLeast("fd_threshold", "", "process_open_fds", float64(p.FDThreshold)),
+		atLeast("threads_threshold", "", "process_threads", float64(p.ThreadsThreshold)),
+	) {
+		r.Labels = map[string]string{"process": p.Name}
+		r.Type = "process"
+		r.TargetLabels = []string{"process"}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func cgroupRules(section string, cfg CgroupRules) []Rule {
+	return targetRules(section, "cgroups", "cgroup", []string{"cgroup"}, keysOf(cfg.Cgroups), func(key string) []check {
+		t := CgroupThresholds{
+			CPUThreshold:        cfg.CPUThreshold,
+			MemoryUsedThreshold: cfg.MemoryUsedThreshold,
+			PidsUsedThreshold:   cfg.PidsUsedThreshold,
+			AlertOnOOMKill:      cfg.AlertOnOOMKill,
+		}
+		if key != "" {
+			t = cfg.Cgroups[key]
+		}
+		return []check{
+			atLeast("cpu_threshold", "", "cgroup_cpu_percent", t.CPUThreshold),
+			atLeast("memory_used_threshold", "", "cgroup_memory_used_percent", t.MemoryUsedThreshold),
+			atLeast("pids_used_threshold", "", "cgroup_pids_used_percent", t.PidsUsedThreshold),
+			{key: "alert_on_oom_kill", metric: "cgroup_oom_kills", op: ">", on: t.AlertOnOOMKill},
+		}
+	})
+}
+
+// thermalRules translates a thermal section. crit_margin compares against
+// the headroom to the sensor's own critical limit, which only sensors that
+// export one have.
+func thermalRules(section string, cfg ThermalRules) []Rule {
+	rules := targetRules(section, "sensors", "temperature", []string{"sensor"}, keysOf(cfg.Sensors), func(key string) []check {
+		t := ThermalThresholds{TempThreshold: cfg.TempThreshold, CritMargin: cfg.CritMargin}
+		if key != "" {
+			t = cfg.Sensors[key]
+		}
+		return []check{
+			atLeast("temp_threshold", "", "temperature_celsius", t.TempThreshold),
+			{key: "crit_margin", metric: "temperature_crit_headroom_celsius", op: "<=", threshold: t.CritMargin, on: t.CritMargin > 0},
+		}
+	})
+	return append(rules, checkRules(section,
+		check{key: "alert_on_throttle", alertType: "throttle", metric: "throttle_events", op: ">", on: cfg.AlertOnThrottle},
+	)...)
+}
+
+// logRules translates the rate threshold of a log_files section, raising
+// log:<file>/<rule>. Overrides are keyed by "<file>/<rule>".
+func logRules(section string, cfg LogRules) []Rule {
+	return targetRules(section, "rules", "log", []string{"file", "rule"}, keysOf(cfg.Rules), func(key string) []check {
+		t := LogThresholds{RateThreshold: cfg.RateThreshold}
+		if key != "" {
+			t = cfg.Rules[key]
+		}
+		return []check{atLeast("rate_threshold", "", "log_matches_ps", t.RateThreshold)}
+	})
+}
+
+// probeRules translates a probes section. cert_days_threshold only applies
+// to probes that saw a certificate.
+func probeRules(section string, cfg ProbeRules) []Rule {
+	return targetRules(section, "probes", "probe", []string{"probe"}, keysOf(cfg.Probes), func(key string) []check {
+		t := ProbeThresholds{
+			AlertOnFailure:     cfg.AlertOnFailure,
+			LatencyMsThreshold: cfg.LatencyMsThreshold,
+			CertDaysThreshold:  cfg.CertDaysThreshold,
+		}
+		if key != "" {
+			t = cfg.Probes[key]
+		}
+		return []check{
+			{key: "alert_on_failure", metric: "probe_up", op: "==", threshold: 0, on: t.AlertOnFailure},
+			atLeast("latency_ms_threshold", "", "probe_latency_ms", t.LatencyMsThreshold),
+			{key: "cert_days_threshold", metric: "probe_cert_days", op: "<=", threshold: t.CertDaysThreshold, on: t.CertDaysThreshold > 0},
+		}
+	})
+}
+
+// diskRules translates a disk section. The relative threshold applies to
+// utilization.
+func diskRules(section string, util, await, iops, relative float64) []Rule {
+	rules := targetRules(section, "", "disk", []string{"device"}, nil, func(string) []check {
+		return []check{
+			atLeast("util_threshold", "", "disk_util_percent", util),
+			atLeast("await_ms_threshold", "", "disk_await_ms", await),
+			atLeast("iops_threshold", "", "disk_iops", iops),
+		}
+	})
+	if relative > 0 {
+		r := relativeRule(section, "relative_threshold", "disk_util_percent", relative, "disk")
+		r.TargetLabels = []string{"device"}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func filesystemRules(section string, base FilesystemThresholds, mounts map[string]FilesystemThresholds) []Rule {
+	return targetRules(section, "mounts", "filesystem", []string{"mount"}, keysOf(mounts), func(key string) []check {
+		t := base
+		if key != "" {
+			t = mounts[key]
+		}
+		return []check{
+			atLeast("used_threshold", "", "filesystem_used_percent", t.UsedThreshold),
+			atLeast("inodes_used_threshold", "", "filesystem_inodes_used_percent", t.InodesUsedThreshold),
+		}
+	})
+}
+
+func textfileRules(section string, cfg TextfileAlert) []Rule {
+	return targetRules(section, "files", "textfile", []string{"file"}, keysOf(cfg.Files), func(key string) []check {
+		t := TextfileThresholds{MaxAgeSec: cfg.MaxAgeSec, AlertOnError: cfg.AlertOnError}
+		if key != "" {
+			t = cfg.Files[key]
+		}
+		return []check{
+			atLeast("max_age_sec", "", "textfile_age_sec", float64(t.MaxAgeSec)),
+			{key: "alert_on_error", metric: "textfile_error", op: "==", threshold: 1, on: t.AlertOnError},
+		}
+	})
+}
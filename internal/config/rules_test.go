@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// describe renders the parts of a translated rule the engines act on.
+func describe(rules []Rule) []string {
+	var lines []string
+	for _, r := range rules {
+		line := fmt.Sprintf("%s: %s %s %g", r.Name, r.Metric, r.Op, r.Threshold)
+		if r.Relative {
+			line += " relative"
+		}
+		if len(r.Labels) > 0 {
+			line += fmt.Sprintf(" labels=%v", r.Labels)
+		}
+		if r.Type != "" {
+			line += " -> " + r.Type
+		}
+		if len(r.TargetLabels) > 0 {
+			line += fmt.Sprintf(":%v", r.TargetLabels)
+		}
+		if len(r.Overrides) > 0 {
+			line += fmt.Sprintf(" override=%q of %v", r.Override, r.Overrides)
+		}
+		line += fmt.Sprintf(" [%s for=%d %s]", r.Variant, r.ForSec, r.Severity)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// TestAlertRules checks how the alert sections translate to rules, with
+// their clear and severity tier variants.
+func TestAlertRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "cpu with clear and tiers",
+			config: `
+alerts:
+  cpu:
+    enabled: true
+    for_sec: 30
+    absolute_threshold: 75
+    core_threshold: 85
+    severity: warning
+    clear: {absolute_threshold: 70}
+    severities:
+      critical: {absolute_threshold: 90, for_sec: 10}
+`,
+			want: []string{
+				"alerts.cpu.absolute_threshold: cpu_usage_percent >= 75 -> cpu [ for=30 warning]",
+				"alerts.cpu.core_threshold: cpu_core_usage_percent >= 85 -> cpu_core:[core] [ for=30 warning]",
+				"alerts.cpu.absolute_threshold: cpu_usage_percent >= 70 -> cpu [clear for=30 warning]",
+				"alerts.cpu.core_threshold: cpu_core_usage_percent >= 85 -> cpu_core:[core] [clear for=30 warning]",
+				"alerts.cpu.absolute_threshold: cpu_usage_percent >= 90 -> cpu [critical for=10 critical]",
+			},
+		},
+		{
+			name: "disabled and zero thresholds",
+			config: `
+alerts:
+  cpu: {enabled: false, absolute_threshold: 75}
+  memory: {enabled: true, absolute_threshold: 0, swap_used_threshold: 0, major_faults_ps_threshold: 100}
+`,
+			want: []string{
+				"alerts.memory.absolute_threshold: mem_used_percent >= 0 -> memory [ for=0 critical]",
+				"alerts.memory.major_faults_ps_threshold: major_faults_ps >= 100 -> major_faults [ for=0 critical]",
+			},
+		},
+		{
+			name: "filesystem overrides",
+			config: `
+alerts:
+  filesystem:
+    enabled: true
+    used_threshold: 90
+    mounts:
+      /var: {used_threshold: 80}
+      /data*: {inodes_used_threshold: 95}
+`,
+			want: []string{
+				`alerts.filesystem.used_threshold: filesystem_used_percent >= 90 -> filesystem:[mount] override="" of [/data* /var] [ for=0 critical]`,
+				`alerts.filesystem.mounts./data*.inodes_used_threshold: filesystem_inodes_used_percent >= 95 -> filesystem:[mount] override="/data*" of [/data* /var] [ for=0 critical]`,
+				`alerts.filesystem.mounts./var.used_threshold: filesystem_used_percent >= 80 -> filesystem:[mount] override="/var" of [/data* /var] [ for=0 critical]`,
+			},
+		},
+		{
+			name: "network with the single interface",
+			config: `
+interface: eth0
+alerts:
+  network: {enabled: true, rx_mbps_threshold: 0, tx_mbps_threshold: 100}
+`,
+			want: []string{
+				"alerts.network.rx_mbps_threshold: net_rx_mbps >= 0 labels=map[interface:eth0] -> network [ for=0 critical]",
+				"alerts.network.tx_mbps_threshold: net_tx_mbps >= 100 labels=map[interface:eth0] -> network [ for=0 critical]",
+			},
+		},
+		{
+			name: "network with an interfaces list",
+			config: `
+interfaces: [eth0, eth1]
+alerts:
+  network: {enabled: true, rx_mbps_threshold: 0, tx_mbps_threshold: 100}
+`,
+			want: []string{
+				"alerts.network.tx_mbps_threshold: net_tx_mbps >= 100 -> network:[interface] [ for=0 critical]",
+			},
+		},
+		{
+			name: "declared rules",
+			config: `
+rules:
+  - name: queue
+    metric: queue_depth
+    op: ">"
+    threshold: 100
+    for_sec: 60
+    severity: page
+  - name: noisy
+    metric: cpu_usage_percent
+    threshold: 50
+    action: log
+`,
+			want: []string{
+				"queue: queue_depth > 100 [ for=60 page]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadConfig(t, tt.config)
+			if got := describe(cfg.AlertRules()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AlertRules() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSpikeRules checks how the spike sections translate to rules.
+func TestSpikeRules(t *testing.T) {
+	cfg := loadConfig(t, `
+spikes:
+  memory:
+    enabled: true
+    absolute_threshold: 85
+    relative_threshold: 20
+    clear: {absolute_threshold: 80}
+  custom:
+    - name: backlog
+      metric: queue_depth
+      threshold: 10
+rules:
+  - name: noisy
+    metric: cpu_usage_percent
+    threshold: 50
+    action: log
+`)
+
+	want := []string{
+		"spikes.memory.absolute_threshold: mem_used_percent >= 85 -> memory [ for=0 critical]",
+		"spikes.memory.relative_threshold: mem_used_percent >= 20 relative -> memory [ for=0 critical]",
+		"spikes.memory.absolute_threshold: mem_used_percent >= 80 -> memory [clear for=0 critical]",
+		"spikes.memory.relative_threshold: mem_used_percent >= 20 relative -> memory [clear for=0 critical]",
+		"backlog: queue_depth >= 10 -> custom:backlog [ for=0 critical]",
+		"noisy: cpu_usage_percent >= 50 [ for=0 critical]",
+	}
+	if got := describe(cfg.SpikeRules()); !reflect.DeepEqual(got, want) {
+		t.Errorf("SpikeRules() =\n%q\nwant\n%q", got, want)
+	}
+}
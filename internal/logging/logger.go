@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"system-sentinel/internal/config"
 	"system-sentinel/internal/metrics"
 )
 
@@ -19,11 +21,16 @@ type Logger struct {
 }
 
 type LogEntry struct {
-	Timestamp string                  `json:"timestamp"`
-	Type      string                  `json:"type"`
-	Metric    string                  `json:"metric"`
-	Reasons   []string                `json:"reasons,omitempty"`
-	Metrics   metrics.MetricsSnapshot `json:"metrics"`
+	Timestamp    string                  `json:"timestamp"`
+	Type         string                  `json:"type"`
+	Metric       string                  `json:"metric"`
+	Reasons      []string                `json:"reasons,omitempty"`
+	Severity     string                  `json:"severity,omitempty"`
+	Severities   map[string]string       `json:"severities,omitempty"`
+	FiredAt      string                  `json:"fired_at,omitempty"`
+	DurationSec  float64                 `json:"duration_sec,omitempty"`
+	Metrics      metrics.MetricsSnapshot `json:"metrics"`
+	TopProcesses *metrics.TopProcesses   `json:"top_processes,omitempty"`
 }
 
 func NewLogger(logDir string) (*Logger, error) {
@@ -44,22 +51,122 @@ func (l *Logger) LogSample(snap metrics.MetricsSnapshot) error {
 }
 
 func (l *Logger) LogSpike(snap metrics.MetricsSnapshot, spikeTypes []string) error {
-	metric := "multi"
-	if len(spikeTypes) == 1 {
-		metric = spikeTypes[0]
+	return l.log("spike", EventMetric(spikeTypes), entryReasons(spikeTypes, snap), snap)
+}
+
+// LogAlert records firing alert types with the severity of each and the
+// highest among them.
+func (l *Logger) LogAlert(snap metrics.MetricsSnapshot, alertTypes []string, severities map[string]string) error {
+	entry := l.entry("alert", EventMetric(alertTypes), entryReasons(alertTypes, snap), snap)
+	entry.Severity = EventSeverity(alertTypes, severities)
+	entry.Severities = make(map[string]string, len(alertTypes))
+	for _, t := range alertTypes {
+		entry.Severities[t] = severities[t]
+	}
+	return l.write(entry)
+}
+
+// LogPending records alert types whose condition started holding but has
+// not yet lasted the rule's for_sec.
+func (l *Logger) LogPending(snap metrics.MetricsSnapshot, alertTypes []string) error {
+	return l.log("pending", EventMetric(alertTypes), entryReasons(alertTypes, snap), snap)
+}
+
+// LogResolved records that a firing alert type's condition cleared, with
+// the highest severity it reached, when it fired and how long it lasted.
+func (l *Logger) LogResolved(snap metrics.MetricsSnapshot, alertType, severity string, firedAt time.Time, duration time.Duration) error {
+	entry := l.entry("alert_resolved", EventMetric([]string{alertType}), []string{alertType}, snap)
+	entry.Severity = severity
+	entry.FiredAt = firedAt.Format(time.RFC3339)
+	entry.DurationSec = duration.Seconds()
+	return l.write(entry)
+}
+
+// LogFlapping records alert types that started flapping (started is true)
+// or settled again.
+func (l *Logger) LogFlapping(snap metrics.MetricsSnapshot, alertTypes []string, started bool) error {
+	eventType := "flapping_stopped"
+	if started {
+		eventType = "flapping"
 	}
-	return l.log("spike", metric, spikeTypes, snap)
+	return l.log(eventType, EventMetric(alertTypes), alertTypes, snap)
 }
 
-func (l *Logger) LogAlert(snap metrics.MetricsSnapshot, alertTypes []string) error {
-	metric := "multi"
-	if len(alertTypes) == 1 {
-		metric = alertTypes[0]
+// entryReasons returns the spike or alert types for a log entry. Log rule
+// types ("log:app/errors") are expanded to one "log:app/errors: <line>"
+// entry per matched line kept in the snapshot.
+func entryReasons(types []string, snap metrics.MetricsSnapshot) []string {
+	reasons := make([]string, 0, len(types))
+	for _, t := range types {
+		rule, ok := strings.CutPrefix(t, "log:")
+		if !ok {
+			reasons = append(reasons, t)
+			continue
+		}
+
+		var lines []string
+		for _, m := range snap.LogMatches {
+			if m.File+"/"+m.Rule == rule {
+				lines = m.Lines
+				break
+			}
+		}
+		if len(lines) == 0 {
+			reasons = append(reasons, t)
+			continue
+		}
+		for _, line := range lines {
+			reasons = append(reasons, t+": "+line)
+		}
 	}
-	return l.log("alert", metric, alertTypes, snap)
+	return reasons
+}
+
+// EventMetric names the metric shared by a set of spike or alert types. Types
+// for per-target metrics carry the target after a colon ("disk:sda"), which is
+// dropped here; mixed metrics report "multi".
+func EventMetric(types []string) string {
+	metric := ""
+	for _, t := range types {
+		name, _, _ := strings.Cut(t, ":")
+		if metric != "" && metric != name {
+			return "multi"
+		}
+		metric = name
+	}
+	if metric == "" {
+		return "multi"
+	}
+	return metric
+}
+
+// EventSeverity returns the highest severity among a set of alert types.
+func EventSeverity(types []string, severities map[string]string) string {
+	severity := ""
+	for _, t := range types {
+		if config.SeverityRank(severities[t]) > config.SeverityRank(severity) {
+			severity = severities[t]
+		}
+	}
+	return severity
 }
 
 func (l *Logger) log(eventType, metric string, reasons []string, snap metrics.MetricsSnapshot) error {
+	return l.write(l.entry(eventType, metric, reasons, snap))
+}
+
+func (l *Logger) entry(eventType, metric string, reasons []string, snap metrics.MetricsSnapshot) LogEntry {
+	return LogEntry{
+		Timestamp:    snap.Timestamp.Format(time.RFC3339),
+		Type:         eventType,
+		Metric:       metric,
+		Reasons:      reasons,
+		Metrics:      snap,
+		TopProcesses: snap.TopProcesses,
+	}
+}
+
+func (l *Logger) write(entry LogEntry) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -67,14 +174,6 @@ func (l *Logger) log(eventType, metric string, reasons []string, snap metrics.Me
 		return err
 	}
 
-	entry := LogEntry{
-		Timestamp: snap.Timestamp.Format(time.RFC3339),
-		Type:      eventType,
-		Metric:    metric,
-		Reasons:   reasons,
-		Metrics:   snap,
-	}
-
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("failed to marshal log entry: %w", err)
@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupSource reports cgroup v2 groups matching the configured globs.
+type cgroupSource struct {
+	cgroupRoot     string
+	cgroupPatterns []string
+	prevCgroups    map[string]cgroupCounters
+	prevCgroupTime time.Time
+}
+
+func (s *cgroupSource) Name() string { return "cgroup" }
+
+func (s *cgroupSource) Init() error { return nil }
+
+func (s *cgroupSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	cgroups, err := s.collectCgroups(snap.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Cgroups = cgroups
+	return nil, nil
+}
+
+type cgroupCounters struct {
+	usageUsec  uint64
+	readBytes  uint64
+	writeBytes uint64
+	oomKills   uint64
+}
+
+// collectCgroups walks the cgroup v2 hierarchy and reports every cgroup whose
+// path matches one of the configured globs. Hosts without a unified
+// hierarchy report nothing.
+func (s *cgroupSource) collectCgroups(now time.Time) ([]CgroupStats, error) {
+	if len(s.cgroupPatterns) == 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(s.cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, nil
+	}
+
+	var paths []string
+	err := filepath.WalkDir(s.cgroupRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups can vanish mid-walk.
+			if p == s.cgroupRoot {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() || p == s.cgroupRoot {
+			return nil
+		}
+		rel, _ := filepath.Rel(s.cgroupRoot, p)
+		if s.watchCgroup(rel) {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prev := s.prevCgroups
+	prevTime := s.prevCgroupTime
+	current := make(map[string]cgroupCounters, len(paths))
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	stats := make([]CgroupStats, 0, len(paths))
+	for _, rel := range paths {
+		dir := filepath.Join(s.cgroupRoot, rel)
+		cg := CgroupStats{Path: rel}
+
+		var counters cgroupCounters
+		counters.usageUsec = readKeyedValue(filepath.Join(dir, "cpu.stat"), "usage_usec")
+		counters.oomKills = readKeyedValue(filepath.Join(dir, "memory.events"), "oom_kill")
+		counters.readBytes, counters.writeBytes = readIOStat(filepath.Join(dir, "io.stat"))
+		current[rel] = counters
+
+		cg.MemoryCurrentBytes, _ = readSysUint(filepath.Join(dir, "memory.current"))
+		cg.MemoryMaxBytes = readLimit(filepath.Join(dir, "memory.max"))
+		if cg.MemoryMaxBytes > 0 {
+			cg.MemoryUsedPercent = float64(cg.MemoryCurrentBytes) / float64(cg.MemoryMaxBytes) * 100.0
+		}
+		cg.OOMEvents = readKeyedValue(filepath.Join(dir, "memory.events"), "oom")
+		cg.OOMKills = counters.oomKills
+
+		cg.PidsCurrent, _ = readSysUint(filepath.Join(dir, "pids.current"))
+		cg.PidsMax = readLimit(filepath.Join(dir, "pids.max"))
+		if cg.PidsMax > 0 {
+			cg.PidsUsedPercent = float64(cg.PidsCurrent) / float64(cg.PidsMax) * 100.0
+		}
+
+		if old, ok := prev[rel]; ok {
+			if counters.usageUsec >= old.usageUsec {
+				cg.CPUUsagePercent = float64(counters.usageUsec-old.usageUsec) / 1e6 / deltaTime * 100.0
+			}
+			if counters.readBytes >= old.readBytes {
+				cg.IOReadBytesPS = float64(counters.readBytes-old.readBytes) / deltaTime
+			}
+			if counters.writeBytes >= old.writeBytes {
+				cg.IOWriteBytesPS = float64(counters.writeBytes-old.writeBytes) / deltaTime
+			}
+			if counters.oomKills >= old.oomKills {
+				cg.NewOOMKills = counters.oomKills - old.oomKills
+			}
+		}
+
+		stats = append(stats, cg)
+	}
+
+	s.prevCgroups = current
+	s.prevCgroupTime = now
+
+	return stats, nil
+}
+
+// watchCgroup matches a cgroup path relative to the root. Patterns with a
+// slash match the whole path ("system.slice/*.service"); patterns without
+// one match the last element at any depth ("docker-*.scope").
+func (s *cgroupSource) watchCgroup(rel string) bool {
+	for _, pattern := range s.cgroupPatterns {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readKeyedValue returns the value for key in a flat "key value" file such
+// as cpu.stat or memory.events, or 0 when either is missing.
+func readKeyedValue(file, key string) uint64 {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), " ")
+		if ok && k == key {
+			val, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			return val
+		}
+	}
+	return 0
+}
+
+// readIOStat sums rbytes and wbytes across every device in io.stat.
+func readIOStat(file string) (uint64, uint64) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	var read, write uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text())[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			val, _ := strconv.ParseUint(v, 10, 64)
+			switch k {
+			case "rbytes":
+				read += val
+			case "wbytes":
+				write += val
+			}
+		}
+	}
+	return read, write
+}
+
+// readLimit parses a cgroup limit file, returning 0 for "max" (unlimited)
+// or a missing file.
+func readLimit(file string) uint64 {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	val, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return val
+}
@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cpuStats struct {
+	user    uint64
+	nice    uint64
+	system  uint64
+	idle    uint64
+	iowait  uint64
+	irq     uint64
+	softirq uint64
+	steal   uint64
+}
+
+func (s cpuStats) total() uint64 {
+	return s.user + s.nice + s.system + s.idle + s.iowait + s.irq + s.softirq + s.steal
+}
+
+// procStatCounters holds the non-CPU lines of /proc/stat.
+type procStatCounters struct {
+	ctxt         uint64
+	intr         uint64
+	forks        uint64
+	procsRunning uint64
+	procsBlocked uint64
+}
+
+// cpuSource reads /proc/stat. Besides CPU usage it fills the scheduler
+// counters on snap.Load, since they come from the same file.
+type cpuSource struct {
+	prevCPUStats     cpuStats
+	prevCoreStats    map[string]cpuStats
+	prevProcStat     procStatCounters
+	prevProcStatTime time.Time
+	initialized      bool
+}
+
+func (s *cpuSource) Name() string { return "cpu" }
+
+func (s *cpuSource) Init() error { return nil }
+
+func (s *cpuSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	cpu, err := s.collectCPU()
+	if err != nil {
+		return nil, err
+	}
+	snap.CPUUsagePercent = cpu.usage
+	snap.CPUTimes = cpu.times
+	snap.CPUCores = cpu.cores
+
+	s.collectScheduler(snap.Timestamp, cpu.counters, &snap.Load)
+
+	return nil, nil
+}
+
+type cpuInfo struct {
+	usage    float64
+	times    CPUTimes
+	cores    []CPUCoreStats
+	counters procStatCounters
+}
+
+func (s *cpuSource) collectCPU() (cpuInfo, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuInfo{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// The intr line lists every IRQ and can outgrow the default buffer.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		return cpuInfo{}, fmt.Errorf("empty /proc/stat")
+	}
+
+	stats, ok := parseCPULine(scanner.Text())
+	if !ok || !strings.HasPrefix(scanner.Text(), "cpu ") {
+		return cpuInfo{}, fmt.Errorf("invalid cpu line")
+	}
+
+	var coreNames []string
+	var counters procStatCounters
+	coreStats := make(map[string]cpuStats)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "cpu") {
+			core, ok := parseCPULine(line)
+			if !ok {
+				continue
+			}
+			name := strings.Fields(line)[0]
+			coreNames = append(coreNames, name)
+			coreStats[name] = core
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		val, _ := strconv.ParseUint(fields[1], 10, 64)
+		switch fields[0] {
+		case "ctxt":
+			counters.ctxt = val
+		case "intr":
+			counters.intr = val
+		case "processes":
+			counters.forks = val
+		case "procs_running":
+			counters.procsRunning = val
+		case "procs_blocked":
+			counters.procsBlocked = val
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuInfo{}, err
+	}
+
+	if !s.initialized {
+		s.prevCPUStats = stats
+		s.prevCoreStats = coreStats
+		s.initialized = true
+
+		info := cpuInfo{counters: counters}
+		for _, name := range coreNames {
+			info.cores = append(info.cores, CPUCoreStats{Name: name})
+		}
+		return info, nil
+	}
+
+	info := cpuInfo{counters: counters}
+	info.usage, info.times = cpuPercents(stats, s.prevCPUStats)
+
+	for _, name := range coreNames {
+		core := CPUCoreStats{Name: name}
+		if prev, ok := s.prevCoreStats[name]; ok {
+			core.UsagePercent, core.Times = cpuPercents(coreStats[name], prev)
+		}
+		info.cores = append(info.cores, core)
+	}
+
+	s.prevCPUStats = stats
+	s.prevCoreStats = coreStats
+
+	return info, nil
+}
+
+// collectScheduler fills the task counts and the context switch, interrupt
+// and fork rates from the non-CPU lines of /proc/stat.
+func (s *cpuSource) collectScheduler(now time.Time, counters procStatCounters, load *LoadStats) {
+	load.ProcsRunning = counters.procsRunning
+	load.ProcsBlocked = counters.procsBlocked
+
+	prev := s.prevProcStat
+	prevTime := s.prevProcStatTime
+	s.prevProcStat = counters
+	s.prevProcStatTime = now
+
+	if prevTime.IsZero() {
+		return
+	}
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	load.ContextSwitchesPS = float64(counters.ctxt-prev.ctxt) / deltaTime
+	load.InterruptsPS = float64(counters.intr-prev.intr) / deltaTime
+	load.ForksPS = float64(counters.forks-prev.forks) / deltaTime
+}
+
+func parseCPULine(line string) (cpuStats, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 8 || !strings.HasPrefix(fields[0], "cpu") {
+		return cpuStats{}, false
+	}
+
+	var stats cpuStats
+	stats.user, _ = strconv.ParseUint(fields[1], 10, 64)
+	stats.nice, _ = strconv.ParseUint(fields[2], 10, 64)
+	stats.system, _ = strconv.ParseUint(fields[3], 10, 64)
+	stats.idle, _ = strconv.ParseUint(fields[4], 10, 64)
+	stats.iowait, _ = strconv.ParseUint(fields[5], 10, 64)
+	stats.irq, _ = strconv.ParseUint(fields[6], 10, 64)
+	stats.softirq, _ = strconv.ParseUint(fields[7], 10, 64)
+	if len(fields) > 8 {
+		stats.steal, _ = strconv.ParseUint(fields[8], 10, 64)
+	}
+
+	return stats, true
+}
+
+// cpuPercents returns overall usage and the per-category breakdown between
+// two samples. Usage deliberately counts iowait as idle time, since a CPU
+// waiting on I/O is free to run other work; this is documented in the README.
+// The breakdown keeps iowait and steal separate so they stay visible, and
+// SnapshotSamples adds iowait back for cpu_usage_incl_iowait_percent.
+func cpuPercents(curr, prev cpuStats) (float64, CPUTimes) {
+	totalDelta := curr.total() - prev.total()
+	if totalDelta == 0 || curr.total() < prev.total() {
+		return 0.0, CPUTimes{}
+	}
+
+	delta := func(c, p uint64) uint64 {
+		if c < p {
+			return 0
+		}
+		return c - p
+	}
+	pct := func(c, p uint64) float64 {
+		return float64(delta(c, p)) / float64(totalDelta) * 100.0
+	}
+
+	// Per-CPU iowait can go backwards, so each counter is guarded on its
+	// own rather than subtracting the sums.
+	idleDelta := delta(curr.idle, prev.idle) + delta(curr.iowait, prev.iowait)
+	if idleDelta > totalDelta {
+		idleDelta = totalDelta
+	}
+	usage := (1.0 - float64(idleDelta)/float64(totalDelta)) * 100.0
+
+	times := CPUTimes{
+		UserPercent:    pct(curr.user, prev.user),
+		NicePercent:    pct(curr.nice, prev.nice),
+		SystemPercent:  pct(curr.system, prev.system),
+		IdlePercent:    pct(curr.idle, prev.idle),
+		IOWaitPercent:  pct(curr.iowait, prev.iowait),
+		IRQPercent:     pct(curr.irq, prev.irq),
+		SoftIRQPercent: pct(curr.softirq, prev.softirq),
+		StealPercent:   pct(curr.steal, prev.steal),
+	}
+
+	return usage, times
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sectorSize = 512
+
+// diskSource reads /proc/diskstats for the configured devices, or for every
+// whole disk when none are configured.
+type diskSource struct {
+	disks         []string
+	prevDiskStats map[string]diskCounters
+	prevDiskTime  time.Time
+}
+
+func (s *diskSource) Name() string { return "disk" }
+
+func (s *diskSource) Init() error { return nil }
+
+func (s *diskSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	disks, err := s.collectDisks(snap.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Disks = disks
+	return nil, nil
+}
+
+type diskCounters struct {
+	readsCompleted  uint64
+	sectorsRead     uint64
+	msReading       uint64
+	writesCompleted uint64
+	sectorsWritten  uint64
+	msWriting       uint64
+	msDoingIO       uint64
+}
+
+func (s *diskSource) collectDisks(now time.Time) ([]DiskStats, error) {
+	file, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	current := make(map[string]diskCounters)
+	var order []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 14 {
+			continue
+		}
+
+		device := fields[2]
+		if !s.watchDisk(device) {
+			continue
+		}
+
+		var counters diskCounters
+		counters.readsCompleted, _ = strconv.ParseUint(fields[3], 10, 64)
+		counters.sectorsRead, _ = strconv.ParseUint(fields[5], 10, 64)
+		counters.msReading, _ = strconv.ParseUint(fields[6], 10, 64)
+		counters.writesCompleted, _ = strconv.ParseUint(fields[7], 10, 64)
+		counters.sectorsWritten, _ = strconv.ParseUint(fields[9], 10, 64)
+		counters.msWriting, _ = strconv.ParseUint(fields[10], 10, 64)
+		counters.msDoingIO, _ = strconv.ParseUint(fields[12], 10, 64)
+
+		current[device] = counters
+		order = append(order, device)
+	}
+
+	for _, device := range s.disks {
+		if _, ok := current[device]; !ok {
+			return nil, fmt.Errorf("device %s not found", device)
+		}
+	}
+
+	prev := s.prevDiskStats
+	prevTime := s.prevDiskTime
+	s.prevDiskStats = current
+	s.prevDiskTime = now
+
+	if prev == nil {
+		stats := make([]DiskStats, 0, len(order))
+		for _, device := range order {
+			stats = append(stats, DiskStats{Device: device})
+		}
+		return stats, nil
+	}
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	stats := make([]DiskStats, 0, len(order))
+	for _, device := range order {
+		curr := current[device]
+		old, ok := prev[device]
+		if !ok {
+			stats = append(stats, DiskStats{Device: device})
+			continue
+		}
+
+		reads := counterDelta(curr.readsCompleted, old.readsCompleted)
+		writes := counterDelta(curr.writesCompleted, old.writesCompleted)
+		ios := reads + writes
+
+		stat := DiskStats{
+			Device:       device,
+			ReadBytesPS:  float64(counterDelta(curr.sectorsRead, old.sectorsRead)*sectorSize) / deltaTime,
+			WriteBytesPS: float64(counterDelta(curr.sectorsWritten, old.sectorsWritten)*sectorSize) / deltaTime,
+			ReadIOPS:     float64(reads) / deltaTime,
+			WriteIOPS:    float64(writes) / deltaTime,
+		}
+
+		if ios > 0 {
+			waited := counterDelta(curr.msReading, old.msReading) + counterDelta(curr.msWriting, old.msWriting)
+			stat.AwaitMs = float64(waited) / float64(ios)
+		}
+
+		stat.UtilPercent = float64(counterDelta(curr.msDoingIO, old.msDoingIO)) / (deltaTime * 1000.0) * 100.0
+		if stat.UtilPercent > 100.0 {
+			stat.UtilPercent = 100.0
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// watchDisk reports whether a /proc/diskstats device should be sampled. With
+// no devices configured, every whole disk except loop and ram devices is used.
+func (s *diskSource) watchDisk(device string) bool {
+	if len(s.disks) > 0 {
+		return containsString(s.disks, device)
+	}
+
+	if strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram") || strings.HasPrefix(device, "zram") {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join("/sys/block", device))
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"system-sentinel/internal/config"
+)
+
+// statfsTimeout bounds how long a sample waits for statfs on one mount. A
+// hung network mount would otherwise stop all sampling.
+const statfsTimeout = 2 * time.Second
+
+type filesystemSource struct {
+	filesystems config.Filesystems
+
+	mu sync.Mutex
+	// hung holds, for each mount whose statfs timed out, the channel that
+	// call reports its result on. The mount is skipped until that call
+	// returns, rather than piling up more blocked calls.
+	hung map[string]chan statfsResult
+}
+
+type statfsResult struct {
+	st  syscall.Statfs_t
+	err error
+}
+
+func (s *filesystemSource) Name() string { return "filesystem" }
+
+func (s *filesystemSource) Init() error { return nil }
+
+func (s *filesystemSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	filesystems, err := s.collectFilesystems()
+	if err != nil {
+		return nil, err
+	}
+	snap.Filesystems = filesystems
+	return nil, nil
+}
+
+type mountEntry struct {
+	device     string
+	mountPoint string
+	fsType     string
+}
+
+func (s *filesystemSource) collectFilesystems() ([]FilesystemStats, error) {
+	mounts, err := readMounts("/proc/self/mounts")
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []FilesystemStats
+	seen := make(map[string]bool)
+
+	for _, m := range mounts {
+		if seen[m.mountPoint] || !s.watchMount(m) {
+			continue
+		}
+
+		st, err := s.statfs(m.mountPoint)
+		if err != nil {
+			if len(s.filesystems.MountPoints) > 0 {
+				return nil, err
+			}
+			continue
+		}
+		if st.Blocks == 0 {
+			continue
+		}
+		seen[m.mountPoint] = true
+
+		blockSize := uint64(st.Bsize)
+		total := st.Blocks * blockSize
+		free := st.Bavail * blockSize
+		used := (st.Blocks - st.Bfree) * blockSize
+
+		fs := FilesystemStats{
+			MountPoint:  m.mountPoint,
+			Device:      m.device,
+			FSType:      m.fsType,
+			TotalBytes:  total,
+			UsedBytes:   used,
+			FreeBytes:   free,
+			InodesTotal: st.Files,
+			InodesFree:  st.Ffree,
+			InodesUsed:  st.Files - st.Ffree,
+		}
+
+		// Match df: reserved blocks count as neither used nor available.
+		if used+free > 0 {
+			fs.UsedPercent = float64(used) / float64(used+free) * 100.0
+		}
+		if fs.InodesTotal > 0 {
+			fs.InodesUsedPercent = float64(fs.InodesUsed) / float64(fs.InodesTotal) * 100.0
+		}
+
+		stats = append(stats, fs)
+	}
+
+	for _, mountPoint := range s.filesystems.MountPoints {
+		if !seen[mountPoint] {
+			return nil, fmt.Errorf("mount point %s not found", mountPoint)
+		}
+	}
+
+	return stats, nil
+}
+
+// statfs runs statfs on mountPoint in the background and gives up after
+// statfsTimeout. A mount whose timed out call is still blocked is reported
+// as hung without another attempt; once that call returns, the next sample
+// tries again.
+func (s *filesystemSource) statfs(mountPoint string) (syscall.Statfs_t, error) {
+	s.mu.Lock()
+	if pending, ok := s.hung[mountPoint]; ok {
+		select {
+		case <-pending:
+			delete(s.hung, mountPoint)
+		default:
+			s.mu.Unlock()
+			return syscall.Statfs_t{}, fmt.Errorf("statfs %s: still hung", mountPoint)
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan statfsResult, 1)
+	go func() {
+		var st syscall.Statfs_t
+		err := syscall.Statfs(mountPoint, &st)
+		done <- statfsResult{st, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return r.st, fmt.Errorf("statfs %s: %w", mountPoint, r.err)
+		}
+		return r.st, nil
+	case <-time.After(statfsTimeout):
+		s.mu.Lock()
+		if s.hung == nil {
+			s.hung = make(map[string]chan statfsResult)
+		}
+		s.hung[mountPoint] = done
+		s.mu.Unlock()
+		return syscall.Statfs_t{}, fmt.Errorf("statfs %s: timed out after %s", mountPoint, statfsTimeout)
+	}
+}
+
+func (s *filesystemSource) watchMount(m mountEntry) bool {
+	cfg := s.filesystems
+
+	if len(cfg.MountPoints) > 0 && !containsString(cfg.MountPoints, m.mountPoint) {
+		return false
+	}
+	if len(cfg.IncludeFSTypes) > 0 && !matchesFSType(cfg.IncludeFSTypes, m.fsType) {
+		return false
+	}
+	return !matchesFSType(cfg.ExcludeFSTypes, m.fsType)
+}
+
+// matchesFSType reports whether fsType matches any of the names or glob
+// patterns (fuse.*) in list.
+func matchesFSType(list []string, fsType string) bool {
+	for _, pattern := range list {
+		if ok, _ := path.Match(pattern, fsType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func readMounts(path string) ([]mountEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			device:     fields[0],
+			mountPoint: unescapeMountField(fields[1]),
+			fsType:     fields[2],
+		})
+	}
+
+	return mounts, scanner.Err()
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space, etc.) the
+// kernel uses for whitespace in /proc/self/mounts.
+func unescapeMountField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"system-sentinel/internal/config"
+)
+
+// maxPendingKernelEvents bounds the events buffered between samples so a
+// kernel log storm cannot grow memory without limit.
+const maxPendingKernelEvents = 100
+
+// kernelLogPoll is how long the tailer waits at end of file before reading
+// again. /dev/kmsg blocks instead, so this only applies to plain files.
+const kernelLogPoll = 250 * time.Millisecond
+
+// oomVictimPattern matches the kernel's OOM killer report, e.g.
+// "Out of memory: Killed process 1234 (java) total-vm:..." and the memory
+// cgroup variant. Older kernels print "Kill process".
+var oomVictimPattern = regexp.MustCompile(`Kill(?:ed)? process (\d+) \(([^)]*)\)`)
+
+// kmsgTimestampPattern strips the "[  123.456789] " prefix dmesg adds, so
+// saved dmesg output can stand in for /dev/kmsg.
+var kmsgTimestampPattern = regexp.MustCompile(`^\[\s*\d+\.\d+\]\s*`)
+
+type kernelRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// kernelLogSource tails the kernel log (normally /dev/kmsg) in the
+// background and hands out what it saw with the next snapshot.
+type kernelLogSource struct {
+	path  string
+	rules []kernelRule
+
+	mu      sync.Mutex
+	events  []KernelEvent
+	victims []OOMVictim
+}
+
+func newKernelLogSource(cfg config.KernelLog) *kernelLogSource {
+	k := &kernelLogSource{path: cfg.Path}
+	for _, rule := range cfg.Rules {
+		k.rules = append(k.rules, kernelRule{name: rule.Name, pattern: regexp.MustCompile(rule.Pattern)})
+	}
+	return k
+}
+
+func (k *kernelLogSource) Name() string { return "kernel_log" }
+
+// Init opens the log and starts tailing it. Only messages written after
+// this are reported.
+func (k *kernelLogSource) Init() error {
+	file, err := os.Open(k.path)
+	if err != nil {
+		return err
+	}
+	// Skip the backlog; on /dev/kmsg this moves past the last record.
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+
+	go k.tail(file)
+	return nil
+}
+
+func (k *kernelLogSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	snap.KernelEvents, snap.OOMVictims = k.drain()
+	return nil, nil
+}
+
+func (k *kernelLogSource) tail(file *os.File) {
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			switch {
+			case err == io.EOF:
+				partial += line
+				time.Sleep(kernelLogPoll)
+				continue
+			case errors.Is(err, syscall.EPIPE):
+				// Records were overwritten before we read them; the next
+				// read resumes at the oldest one still available.
+				partial = ""
+				continue
+			default:
+				return
+			}
+		}
+
+		k.handle(partial + line)
+		partial = ""
+	}
+}
+
+func (k *kernelLogSource) handle(line string) {
+	message := kernelMessage(line)
+	if message == "" {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if m := oomVictimPattern.FindStringSubmatch(message); m != nil && len(k.victims) < maxPendingKernelEvents {
+		pid, _ := strconv.Atoi(m[1])
+		k.victims = append(k.victims, OOMVictim{PID: pid, Name: m[2]})
+	}
+
+	for _, rule := range k.rules {
+		if rule.pattern.MatchString(message) && len(k.events) < maxPendingKernelEvents {
+			k.events = append(k.events, KernelEvent{Rule: rule.name, Message: message})
+		}
+	}
+}
+
+// drain returns and clears everything seen since the previous call.
+func (k *kernelLogSource) drain() ([]KernelEvent, []OOMVictim) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	events, victims := k.events, k.victims
+	k.events, k.victims = nil, nil
+	return events, victims
+}
+
+// kernelMessage extracts the message text from a /dev/kmsg record
+// ("6,1234,5678,-;text"), or from a dmesg-style line when reading a plain
+// file. Continuation lines carrying device properties are dropped.
+func kernelMessage(line string) string {
+	line = strings.TrimRight(line, "\n")
+	if line == "" || line[0] == ' ' {
+		return ""
+	}
+
+	if prefix, message, ok := strings.Cut(line, ";"); ok && isKmsgPrefix(prefix) {
+		return message
+	}
+	return kmsgTimestampPattern.ReplaceAllString(line, "")
+}
+
+func isKmsgPrefix(prefix string) bool {
+	fields := strings.Split(prefix, ",")
+	if len(fields) < 4 {
+		return false
+	}
+	for _, field := range fields[:3] {
+		if _, err := strconv.ParseUint(field, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
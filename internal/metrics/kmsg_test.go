@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"system-sentinel/internal/config"
+)
+
+func TestKernelMessage(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"6,1234,5678,-;eth0: link up\n", "eth0: link up"},
+		{"3,99,100,c;EXT4-fs error (device sda1): bad block\n", "EXT4-fs error (device sda1): bad block"},
+		{" SUBSYSTEM=net\n", ""},
+		{"\n", ""},
+		{"[  123.456789] usb 1-1: new device\n", "usb 1-1: new device"},
+		{"plain message; with a semicolon\n", "plain message; with a semicolon"},
+	}
+	for _, tt := range tests {
+		if got := kernelMessage(tt.line); got != tt.want {
+			t.Errorf("kernelMessage(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestKernelLogSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kmsg")
+	if err := os.WriteFile(path, []byte("6,1,1,-;EXT4-fs error before start\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	k := newKernelLogSource(config.KernelLog{
+		Path: path,
+		Rules: []config.KernelLogRule{
+			{Name: "fs_error", Pattern: `EXT4-fs error`},
+			{Name: "hung_task", Pattern: `blocked for more than \d+ seconds`},
+		},
+	})
+	if err := k.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := "6,2,2,-;eth0: link up\n" +
+		"3,3,3,-;EXT4-fs error (device sda1): bad block\n" +
+		" DEVICE=b8:1\n" +
+		"3,4,4,-;Out of memory: Killed process 4321 (java) total-vm:1024kB, anon-rss:512kB\n" +
+		"[  12.000000] Memory cgroup out of memory: Kill process 77 (worker-1) score 900\n" +
+		"3,5,5,-;INFO: task kworker:12 blocked for more than 120 seconds.\n"
+	if _, err := file.WriteString(lines); err != nil {
+		t.Fatal(err)
+	}
+
+	var snap MetricsSnapshot
+	deadline := time.Now().Add(5 * time.Second)
+	for len(snap.KernelEvents) < 2 || len(snap.OOMVictims) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for kernel events: got %+v and %+v", snap.KernelEvents, snap.OOMVictims)
+		}
+		time.Sleep(10 * time.Millisecond)
+		var next MetricsSnapshot
+		if _, err := k.Collect(&next); err != nil {
+			t.Fatal(err)
+		}
+		snap.KernelEvents = append(snap.KernelEvents, next.KernelEvents...)
+		snap.OOMVictims = append(snap.OOMVictims, next.OOMVictims...)
+	}
+
+	wantEvents := []KernelEvent{
+		{Rule: "fs_error", Message: "EXT4-fs error (device sda1): bad block"},
+		{Rule: "hung_task", Message: "INFO: task kworker:12 blocked for more than 120 seconds."},
+	}
+	if !reflect.DeepEqual(snap.KernelEvents, wantEvents) {
+		t.Errorf("events:\n got %+v\nwant %+v", snap.KernelEvents, wantEvents)
+	}
+
+	wantVictims := []OOMVictim{{PID: 4321, Name: "java"}, {PID: 77, Name: "worker-1"}}
+	if !reflect.DeepEqual(snap.OOMVictims, wantVictims) {
+		t.Errorf("victims:\n got %+v\nwant %+v", snap.OOMVictims, wantVictims)
+	}
+
+	// Everything was drained, so the next sample starts empty.
+	var next MetricsSnapshot
+	if _, err := k.Collect(&next); err != nil {
+		t.Fatal(err)
+	}
+	if next.KernelEvents != nil || next.OOMVictims != nil {
+		t.Errorf("second collect: got %+v and %+v, want nothing", next.KernelEvents, next.OOMVictims)
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadSource reads /proc/loadavg. It runs after the cpu source so the core
+// count is known for per-core load.
+type loadSource struct{}
+
+func (s *loadSource) Name() string { return "load" }
+
+func (s *loadSource) Init() error { return nil }
+
+func (s *loadSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid /proc/loadavg")
+	}
+
+	load := &snap.Load
+	load.Load1, _ = strconv.ParseFloat(fields[0], 64)
+	load.Load5, _ = strconv.ParseFloat(fields[1], 64)
+	load.Load15, _ = strconv.ParseFloat(fields[2], 64)
+
+	if running, total, ok := strings.Cut(fields[3], "/"); ok {
+		load.TasksRunning, _ = strconv.ParseUint(running, 10, 64)
+		load.TasksTotal, _ = strconv.ParseUint(total, 10, 64)
+	}
+
+	if cores := len(snap.CPUCores); cores > 0 {
+		load.Load1PerCore = load.Load1 / float64(cores)
+		load.Load5PerCore = load.Load5 / float64(cores)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"system-sentinel/internal/config"
+)
+
+// logFilePoll is how long a follower waits at end of file, or for a missing
+// file to appear, before trying again.
+const logFilePoll = 250 * time.Millisecond
+
+// maxLogMatchLines bounds the matched lines kept per rule between samples,
+// and maxLogLineBytes the length of each one.
+const (
+	maxLogMatchLines = 5
+	maxLogLineBytes  = 1024
+)
+
+type logRule struct {
+	name     string
+	pattern  *regexp.Regexp
+	critical bool
+	matches  uint64
+	lines    []string
+}
+
+type followedLog struct {
+	name  string
+	path  string
+	rules []*logRule
+}
+
+// logFileSource follows application log files like tail -F and counts the
+// lines matching each rule between samples.
+type logFileSource struct {
+	files []*followedLog
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newLogFileSource(files []config.LogFile) *logFileSource {
+	s := &logFileSource{}
+	for _, f := range files {
+		followed := &followedLog{name: f.Name, path: f.Path}
+		for _, rule := range f.Rules {
+			followed.rules = append(followed.rules, &logRule{
+				name:     rule.Name,
+				pattern:  regexp.MustCompile(rule.Pattern),
+				critical: rule.Critical,
+			})
+		}
+		s.files = append(s.files, followed)
+	}
+	return s
+}
+
+func (s *logFileSource) Name() string { return "log_files" }
+
+// Init starts following every file. Files that exist are read from their
+// current end; files that do not exist yet are read from the start once
+// they appear.
+func (s *logFileSource) Init() error {
+	s.last = time.Now()
+	for _, f := range s.files {
+		go s.follow(f)
+	}
+	return nil
+}
+
+func (s *logFileSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := snap.Timestamp.Sub(s.last).Seconds()
+	if elapsed <= 0 {
+		elapsed = 1.0
+	}
+	s.last = snap.Timestamp
+
+	for _, f := range s.files {
+		for _, rule := range f.rules {
+			snap.LogMatches = append(snap.LogMatches, LogMatchStats{
+				File:      f.name,
+				Path:      f.path,
+				Rule:      rule.name,
+				Critical:  rule.critical,
+				Matches:   rule.matches,
+				MatchesPS: float64(rule.matches) / elapsed,
+				Lines:     rule.lines,
+			})
+			rule.matches = 0
+			rule.lines = nil
+		}
+	}
+	return nil, nil
+}
+
+// follow reads f forever. When the path points at a new inode (the file
+// was rotated) the rest of the old file is read before switching to the
+// new one from its start; when the file shrinks (it was truncated in
+// place) reading restarts at offset 0.
+func (s *logFileSource) follow(f *followedLog) {
+	atStartup := true
+	for {
+		file, err := os.Open(f.path)
+		if err != nil {
+			atStartup = false
+			time.Sleep(logFilePoll)
+			continue
+		}
+
+		var offset int64
+		if atStartup {
+			offset, err = file.Seek(0, io.SeekEnd)
+			if err != nil {
+				file.Close()
+				time.Sleep(logFilePoll)
+				continue
+			}
+			atStartup = false
+		}
+
+		s.read(f, file, offset)
+		file.Close()
+	}
+}
+
+// read consumes file from offset until it is rotated away or fails.
+func (s *logFileSource) read(f *followedLog, file *os.File, offset int64) {
+	reader := bufio.NewReader(file)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+		if err == nil {
+			s.handle(f, partial+line)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			return
+		}
+		partial += line
+
+		current, err := file.Stat()
+		if err != nil {
+			return
+		}
+		latest, err := os.Stat(f.path)
+		switch {
+		case err != nil:
+			// Rotated away and not recreated yet; keep reading the old
+			// file, which the writer may still have open.
+		case !os.SameFile(current, latest):
+			if rest, _ := io.ReadAll(reader); len(rest) > 0 {
+				partial += string(rest)
+			}
+			for _, line := range strings.SplitAfter(partial, "\n") {
+				s.handle(f, line)
+			}
+			return
+		case latest.Size() < offset:
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return
+			}
+			reader.Reset(file)
+			offset = 0
+			partial = ""
+			continue
+		}
+
+		time.Sleep(logFilePoll)
+	}
+}
+
+func (s *logFileSource) handle(f *followedLog, line string) {
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return
+	}
+
+	kept := line
+	if len(kept) > maxLogLineBytes {
+		kept = kept[:maxLogLineBytes]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range f.rules {
+		if !rule.pattern.MatchString(line) {
+			continue
+		}
+		rule.matches++
+		if len(rule.lines) < maxLogMatchLines {
+			rule.lines = append(rule.lines, kept)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memorySource reads /proc/meminfo for memory and swap usage and
+// /proc/vmstat for paging rates and OOM kills.
+type memorySource struct {
+	prevVMStat     vmCounters
+	prevVMStatTime time.Time
+}
+
+func (s *memorySource) Name() string { return "memory" }
+
+func (s *memorySource) Init() error { return nil }
+
+func (s *memorySource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	memInfo, err := s.collectMemory()
+	if err != nil {
+		return nil, err
+	}
+	snap.MemTotalBytes = memInfo.total
+	snap.MemUsedBytes = memInfo.total - memInfo.available
+	snap.MemUsedPercent = float64(snap.MemUsedBytes) / float64(memInfo.total) * 100.0
+	snap.MemBreakdown = memInfo.breakdown
+	snap.SwapTotalBytes = memInfo.swapTotal
+	snap.SwapUsedBytes = memInfo.swapTotal - memInfo.swapFree
+	if memInfo.swapTotal > 0 {
+		snap.SwapUsedPercent = float64(snap.SwapUsedBytes) / float64(memInfo.swapTotal) * 100.0
+	}
+
+	vm, err := s.collectVMStat(snap.Timestamp, memInfo.breakdown.DirtyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("vmstat: %w", err)
+	}
+	snap.SwapInPagesPS = vm.swapInPagesPS
+	snap.SwapOutPagesPS = vm.swapOutPagesPS
+	snap.PageFaultsPS = vm.pageFaultsPS
+	snap.MajorFaultsPS = vm.majorFaultsPS
+	snap.DirtyGrowthBytesPS = vm.dirtyGrowthBytesPS
+	snap.OOMKills = vm.oomKills
+
+	return nil, nil
+}
+
+type memoryInfo struct {
+	total     uint64
+	available uint64
+	swapTotal uint64
+	swapFree  uint64
+	breakdown MemoryBreakdown
+}
+
+func (s *memorySource) collectMemory() (memoryInfo, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return memoryInfo{}, err
+	}
+	defer file.Close()
+
+	var info memoryInfo
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		val, _ := strconv.ParseUint(fields[1], 10, 64)
+		// Values are in kB except for the HugePages_* counts.
+		if len(fields) >= 3 && fields[2] == "kB" {
+			val *= 1024
+		}
+
+		switch fields[0] {
+		case "MemTotal:":
+			info.total = val
+		case "MemAvailable:":
+			info.available = val
+		case "SwapTotal:":
+			info.swapTotal = val
+		case "SwapFree:":
+			info.swapFree = val
+		case "Cached:":
+			info.breakdown.CachedBytes = val
+		case "Buffers:":
+			info.breakdown.BuffersBytes = val
+		case "Dirty:":
+			info.breakdown.DirtyBytes = val
+		case "Writeback:":
+			info.breakdown.WritebackBytes = val
+		case "Slab:":
+			info.breakdown.SlabBytes = val
+		case "SReclaimable:":
+			info.breakdown.SReclaimableBytes = val
+		case "Shmem:":
+			info.breakdown.ShmemBytes = val
+		case "HugePages_Total:":
+			info.breakdown.HugePagesTotal = val
+		case "HugePages_Free:":
+			info.breakdown.HugePagesFree = val
+		case "Hugepagesize:":
+			info.breakdown.HugePageSizeBytes = val
+		}
+	}
+
+	if info.total == 0 {
+		return memoryInfo{}, fmt.Errorf("could not read MemTotal")
+	}
+	if info.available == 0 {
+		info.available = info.total
+	}
+	if info.swapFree > info.swapTotal {
+		info.swapFree = info.swapTotal
+	}
+
+	return info, nil
+}
+
+type vmCounters struct {
+	pswpin     uint64
+	pswpout    uint64
+	pgfault    uint64
+	pgmajfault uint64
+	dirty      uint64
+	oomKill    uint64
+}
+
+type vmStatInfo struct {
+	swapInPagesPS      float64
+	swapOutPagesPS     float64
+	pageFaultsPS       float64
+	majorFaultsPS      float64
+	dirtyGrowthBytesPS float64
+	oomKills           uint64
+}
+
+func (s *memorySource) collectVMStat(now time.Time, dirtyBytes uint64) (vmStatInfo, error) {
+	file, err := os.Open("/proc/vmstat")
+	if err != nil {
+		return vmStatInfo{}, err
+	}
+	defer file.Close()
+
+	counters := vmCounters{dirty: dirtyBytes}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pswpin":
+			counters.pswpin, _ = strconv.ParseUint(value, 10, 64)
+		case "pswpout":
+			counters.pswpout, _ = strconv.ParseUint(value, 10, 64)
+		case "pgfault":
+			counters.pgfault, _ = strconv.ParseUint(value, 10, 64)
+		case "pgmajfault":
+			counters.pgmajfault, _ = strconv.ParseUint(value, 10, 64)
+		case "oom_kill":
+			counters.oomKill, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+
+	prev := s.prevVMStat
+	prevTime := s.prevVMStatTime
+	s.prevVMStat = counters
+	s.prevVMStatTime = now
+
+	if prevTime.IsZero() {
+		return vmStatInfo{}, nil
+	}
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	info := vmStatInfo{
+		swapInPagesPS:      float64(counterDelta(counters.pswpin, prev.pswpin)) / deltaTime,
+		swapOutPagesPS:     float64(counterDelta(counters.pswpout, prev.pswpout)) / deltaTime,
+		pageFaultsPS:       float64(counterDelta(counters.pgfault, prev.pgfault)) / deltaTime,
+		majorFaultsPS:      float64(counterDelta(counters.pgmajfault, prev.pgmajfault)) / deltaTime,
+		dirtyGrowthBytesPS: (float64(counters.dirty) - float64(prev.dirty)) / deltaTime,
+		oomKills:           counterDelta(counters.oomKill, prev.oomKill),
+	}
+
+	return info, nil
+}
@@ -0,0 +1,248 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// networkSource reads /proc/net/dev and /sys/class/net for the configured
+// interfaces, and fills the summed totals alongside the per-interface list.
+// missing holds the configured names last seen missing.
+type networkSource struct {
+	interfaces   []string
+	prevNetStats map[string]netStats
+	prevNetTime  time.Time
+	missing      map[string]bool
+}
+
+func (s *networkSource) Name() string { return "network" }
+
+func (s *networkSource) Init() error { return nil }
+
+func (s *networkSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	interfaces, err := s.collectNetwork(snap.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Interfaces = interfaces
+
+	names := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		names = append(names, iface.Name)
+		snap.NetRxBytesPS += iface.RxBytesPS
+		snap.NetTxBytesPS += iface.TxBytesPS
+	}
+	snap.NetInterface = strings.Join(names, ",")
+	snap.NetRxMbps = snap.NetRxBytesPS * 8.0 / 1_000_000.0
+	snap.NetTxMbps = snap.NetTxBytesPS * 8.0 / 1_000_000.0
+
+	return nil, s.checkMissing(interfaces)
+}
+
+// checkMissing returns an error naming the configured interfaces, other
+// than "all" and glob patterns, that went missing since the last sample.
+// The interfaces found are reported all the same, and a missing one is
+// only named again once it came back and went missing again.
+func (s *networkSource) checkMissing(interfaces []InterfaceStats) error {
+	found := make(map[string]bool, len(interfaces))
+	for _, iface := range interfaces {
+		found[iface.Name] = true
+	}
+
+	var missing []string
+	for _, pattern := range s.interfaces {
+		if pattern == "all" || isGlob(pattern) {
+			continue
+		}
+		if found[pattern] {
+			delete(s.missing, pattern)
+			continue
+		}
+		if !s.missing[pattern] {
+			if s.missing == nil {
+				s.missing = make(map[string]bool)
+			}
+			s.missing[pattern] = true
+			missing = append(missing, pattern)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("interface %s not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+type netStats struct {
+	rxBytes        uint64
+	rxPackets      uint64
+	rxErrors       uint64
+	rxDrops        uint64
+	rxFifo         uint64
+	rxFrame        uint64
+	txBytes        uint64
+	txPackets      uint64
+	txErrors       uint64
+	txDrops        uint64
+	txFifo         uint64
+	collisions     uint64
+	carrierChanges uint64
+}
+
+func (s *networkSource) collectNetwork(now time.Time) ([]InterfaceStats, error) {
+	file, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	current := make(map[string]netStats)
+	var order []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Counters can run into the name ("eth0:123456"), so split on the
+		// colon rather than on whitespace.
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(line[:idx])
+		if !s.watchInterface(name) {
+			continue
+		}
+
+		fields := strings.Fields(line[idx+1:])
+		if len(fields) < 14 {
+			continue
+		}
+
+		var stats netStats
+		stats.rxBytes, _ = strconv.ParseUint(fields[0], 10, 64)
+		stats.rxPackets, _ = strconv.ParseUint(fields[1], 10, 64)
+		stats.rxErrors, _ = strconv.ParseUint(fields[2], 10, 64)
+		stats.rxDrops, _ = strconv.ParseUint(fields[3], 10, 64)
+		stats.rxFifo, _ = strconv.ParseUint(fields[4], 10, 64)
+		stats.rxFrame, _ = strconv.ParseUint(fields[5], 10, 64)
+		stats.txBytes, _ = strconv.ParseUint(fields[8], 10, 64)
+		stats.txPackets, _ = strconv.ParseUint(fields[9], 10, 64)
+		stats.txErrors, _ = strconv.ParseUint(fields[10], 10, 64)
+		stats.txDrops, _ = strconv.ParseUint(fields[11], 10, 64)
+		stats.txFifo, _ = strconv.ParseUint(fields[12], 10, 64)
+		stats.collisions, _ = strconv.ParseUint(fields[13], 10, 64)
+		stats.carrierChanges, _ = readSysUint(filepath.Join(sysClassNet, name, "carrier_changes"))
+
+		current[name] = stats
+		order = append(order, name)
+	}
+
+	prev := s.prevNetStats
+	prevTime := s.prevNetTime
+	s.prevNetStats = current
+	s.prevNetTime = now
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	rate := func(curr, old uint64) float64 {
+		return float64(counterDelta(curr, old)) / deltaTime
+	}
+
+	stats := make([]InterfaceStats, 0, len(order))
+	for _, name := range order {
+		iface := InterfaceStats{Name: name}
+		readLinkState(name, &iface)
+
+		curr := current[name]
+		old, ok := prev[name]
+		if ok {
+			iface.RxBytesPS = rate(curr.rxBytes, old.rxBytes)
+			iface.TxBytesPS = rate(curr.txBytes, old.txBytes)
+			iface.RxMbps = iface.RxBytesPS * 8.0 / 1_000_000.0
+			iface.TxMbps = iface.TxBytesPS * 8.0 / 1_000_000.0
+			iface.RxPacketsPS = rate(curr.rxPackets, old.rxPackets)
+			iface.TxPacketsPS = rate(curr.txPackets, old.txPackets)
+			iface.RxErrorsPS = rate(curr.rxErrors, old.rxErrors)
+			iface.TxErrorsPS = rate(curr.txErrors, old.txErrors)
+			iface.RxDropsPS = rate(curr.rxDrops, old.rxDrops)
+			iface.TxDropsPS = rate(curr.txDrops, old.txDrops)
+			iface.RxFifoErrorsPS = rate(curr.rxFifo, old.rxFifo)
+			iface.TxFifoErrorsPS = rate(curr.txFifo, old.txFifo)
+			iface.RxFrameErrorsPS = rate(curr.rxFrame, old.rxFrame)
+			iface.CollisionsPS = rate(curr.collisions, old.collisions)
+			iface.CarrierChanges = counterDelta(curr.carrierChanges, old.carrierChanges)
+		}
+
+		stats = append(stats, iface)
+	}
+
+	return stats, nil
+}
+
+// readLinkState fills the operational state, speed and duplex from sysfs.
+// Virtual devices reject speed and duplex reads, which leaves them unset.
+func readLinkState(name string, iface *InterfaceStats) {
+	dir := filepath.Join(sysClassNet, name)
+
+	if data, err := os.ReadFile(filepath.Join(dir, "operstate")); err == nil {
+		iface.OperState = strings.TrimSpace(string(data))
+	}
+	iface.LinkUp = iface.OperState == "up" || iface.OperState == "unknown"
+
+	if flags, err := os.ReadFile(filepath.Join(dir, "flags")); err == nil {
+		v, _ := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(string(flags)), "0x"), 16, 64)
+		iface.AdminUp = v&0x1 != 0
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "speed")); err == nil {
+		if speed, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && speed > 0 {
+			iface.SpeedMbps = speed
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "duplex")); err == nil {
+		iface.Duplex = strings.TrimSpace(string(data))
+	}
+}
+
+func readSysUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// watchInterface matches an interface name against the configured names and
+// glob patterns. The special pattern "all" matches every non-loopback device.
+func (s *networkSource) watchInterface(name string) bool {
+	for _, pattern := range s.interfaces {
+		if pattern == "all" {
+			if name != "lo" {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const procPressure = "/proc/pressure"
+
+type pressureSource struct {
+	prevPressure     map[string]pressureTotals
+	prevPressureTime time.Time
+}
+
+func (s *pressureSource) Name() string { return "pressure" }
+
+func (s *pressureSource) Init() error { return nil }
+
+func (s *pressureSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	pressure, err := s.collectPressure(snap.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Pressure = pressure
+	return nil, nil
+}
+
+type pressureTotals struct {
+	some uint64
+	full uint64
+}
+
+// collectPressure reads PSI for cpu, memory and io. Kernels built without
+// PSI, or booted with psi=0, leave Available false instead of failing.
+func (s *pressureSource) collectPressure(now time.Time) (PressureStats, error) {
+	stats := PressureStats{Available: true}
+	current := make(map[string]pressureTotals)
+
+	resources := []struct {
+		name string
+		dst  *PressureResource
+	}{
+		{"cpu", &stats.CPU},
+		{"memory", &stats.Memory},
+		{"io", &stats.IO},
+	}
+
+	deltaTime := now.Sub(s.prevPressureTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	for _, res := range resources {
+		resource, totals, err := readPressureFile(procPressure + "/" + res.name)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+				return PressureStats{}, nil
+			}
+			return PressureStats{}, err
+		}
+
+		if prev, ok := s.prevPressure[res.name]; ok {
+			if totals.some >= prev.some {
+				resource.Some.StallUsPS = float64(totals.some-prev.some) / deltaTime
+			}
+			if totals.full >= prev.full {
+				resource.Full.StallUsPS = float64(totals.full-prev.full) / deltaTime
+			}
+		}
+
+		*res.dst = resource
+		current[res.name] = totals
+	}
+
+	s.prevPressure = current
+	s.prevPressureTime = now
+
+	return stats, nil
+}
+
+func readPressureFile(path string) (PressureResource, pressureTotals, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return PressureResource{}, pressureTotals{}, err
+	}
+	defer file.Close()
+
+	var resource PressureResource
+	var totals pressureTotals
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		var line PressureLine
+		var total uint64
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "avg10":
+				line.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				line.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				line.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				total, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+
+		switch fields[0] {
+		case "some":
+			resource.Some = line
+			totals.some = total
+		case "full":
+			resource.Full = line
+			totals.full = total
+		}
+	}
+
+	return resource, totals, scanner.Err()
+}
@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, which is 100 on every mainstream Linux architecture.
+const clockTicks = 100
+
+// maxCmdlineLen caps the command line kept per process so a single long
+// invocation cannot bloat log entries.
+const maxCmdlineLen = 256
+
+type procKey struct {
+	pid       int
+	startTime uint64
+}
+
+type procSample struct {
+	pid        int
+	name       string
+	state      string
+	ppid       int
+	startTime  uint64
+	cpuTicks   uint64
+	cpuPercent float64
+	rssBytes   uint64
+	threads    int
+}
+
+type processScanner struct {
+	procRoot string
+	pageSize uint64
+	buf      []byte
+	prev     map[procKey]uint64
+	prevTime time.Time
+	last     []procSample
+	cmdlines map[procKey]string
+}
+
+func newProcessScanner(procRoot string) *processScanner {
+	return &processScanner{
+		procRoot: procRoot,
+		pageSize: uint64(os.Getpagesize()),
+		buf:      make([]byte, 4096),
+	}
+}
+
+// processSource reports watched processes and, with every_sample set, the
+// top processes. Both share one /proc scan per sample.
+type processSource struct {
+	procs          *processScanner
+	watches        []*processWatch
+	topN           int
+	topWindow      time.Duration
+	topEverySample bool
+}
+
+func (p *processSource) Name() string { return "processes" }
+
+func (p *processSource) Init() error { return nil }
+
+func (p *processSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	watched, err := p.collectWatchedProcesses()
+	if err != nil {
+		return nil, err
+	}
+	snap.WatchedProcesses = watched
+
+	// Scanning on every sample keeps the baseline that an alert's
+	// TopProcesses measures CPU% against one sample old.
+	if p.topN > 0 {
+		samples, err := p.procs.scanFresh(p.topWindow)
+		if err != nil {
+			return nil, err
+		}
+		if p.topEverySample {
+			snap.TopProcesses = topProcesses(p.procs, samples, p.topN)
+		}
+	}
+
+	return nil, nil
+}
+
+// TopProcesses returns the busiest processes by CPU and by RSS. CPU usage is
+// measured against the previous scan, and is 0 for every process on the
+// first one.
+func (p *processSource) TopProcesses() (*TopProcesses, error) {
+	if p.topN <= 0 {
+		return nil, nil
+	}
+
+	samples, err := p.procs.scanFresh(p.topWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	return topProcesses(p.procs, samples, p.topN), nil
+}
+
+// scanFresh returns process samples with CPU% measured since the previous
+// scan. A scan younger than window is reused as is, so callers within the
+// same sample share one pass over /proc.
+func (s *processScanner) scanFresh(window time.Duration) ([]procSample, error) {
+	if s.prev != nil && time.Since(s.prevTime) < window {
+		return s.last, nil
+	}
+	return s.scan()
+}
+
+// scan reads /proc/[pid]/stat for every process and computes each one's CPU
+// percent since the previous scan.
+func (s *processScanner) scan() ([]procSample, error) {
+	entries, err := os.ReadDir(s.procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(s.prevTime).Seconds()
+	current := make(map[procKey]uint64, len(s.prev))
+	samples := make([]procSample, 0, len(entries))
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		sample, ok := s.readStat(pid)
+		if !ok {
+			continue
+		}
+
+		key := procKey{pid: pid, startTime: sample.startTime}
+		current[key] = sample.cpuTicks
+
+		if prevTicks, ok := s.prev[key]; ok && elapsed > 0 && sample.cpuTicks >= prevTicks {
+			sample.cpuPercent = float64(sample.cpuTicks-prevTicks) / clockTicks / elapsed * 100.0
+		}
+		samples = append(samples, sample)
+	}
+
+	s.prev = current
+	s.prevTime = now
+	s.last = samples
+
+	return samples, nil
+}
+
+func (s *processScanner) readStat(pid int) (procSample, bool) {
+	file, err := os.Open(filepath.Join(s.procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procSample{}, false
+	}
+	n, err := file.Read(s.buf)
+	file.Close()
+	if err != nil || n == 0 {
+		return procSample{}, false
+	}
+	data := s.buf[:n]
+
+	// comm may itself contain spaces and parentheses, so split around the
+	// last closing parenthesis.
+	lparen := bytes.IndexByte(data, '(')
+	rparen := bytes.LastIndexByte(data, ')')
+	if lparen < 0 || rparen < lparen || rparen+2 > len(data) {
+		return procSample{}, false
+	}
+
+	fields := strings.Fields(string(data[rparen+2:]))
+	if len(fields) < 22 {
+		return procSample{}, false
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	startTime, _ := strconv.ParseUint(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+	ppid, _ := strconv.Atoi(fields[1])
+
+	return procSample{
+		pid:       pid,
+		name:      string(data[lparen+1 : rparen]),
+		state:     fields[0],
+		ppid:      ppid,
+		startTime: startTime,
+		cpuTicks:  utime + stime,
+		rssBytes:  rssPages * s.pageSize,
+		threads:   threads,
+	}, true
+}
+
+func topProcesses(s *processScanner, samples []procSample, n int) *TopProcesses {
+	byCPU := make([]procSample, len(samples))
+	copy(byCPU, samples)
+	sort.Slice(byCPU, func(i, j int) bool {
+		return byCPU[i].cpuPercent > byCPU[j].cpuPercent
+	})
+
+	byRSS := make([]procSample, len(samples))
+	copy(byRSS, samples)
+	sort.Slice(byRSS, func(i, j int) bool {
+		return byRSS[i].rssBytes > byRSS[j].rssBytes
+	})
+
+	top := &TopProcesses{}
+	for i := 0; i < n && i < len(byCPU); i++ {
+		top.ByCPU = append(top.ByCPU, s.describe(byCPU[i]))
+	}
+	for i := 0; i < n && i < len(byRSS); i++ {
+		top.ByMemory = append(top.ByMemory, s.describe(byRSS[i]))
+	}
+
+	return top
+}
+
+// describe turns a stat sample into a ProcessInfo, reading status and
+// cmdline only for the handful of processes that made the top list.
+func (s *processScanner) describe(sample procSample) ProcessInfo {
+	info := ProcessInfo{
+		PID:        sample.pid,
+		PPID:       sample.ppid,
+		Name:       sample.name,
+		State:      sample.state,
+		CPUPercent: sample.cpuPercent,
+		RSSBytes:   sample.rssBytes,
+		Threads:    sample.threads,
+		UID:        -1,
+	}
+
+	dir := filepath.Join(s.procRoot, strconv.Itoa(sample.pid))
+	if data, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				continue
+			}
+			switch key {
+			case "Uid":
+				info.UID, _ = strconv.Atoi(fields[0])
+			case "VmRSS":
+				if kb, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+					info.RSSBytes = kb * 1024
+				}
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		info.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+		if len(info.Cmdline) > maxCmdlineLen {
+			info.Cmdline = info.Cmdline[:maxCmdlineLen]
+		}
+	}
+
+	return info
+}
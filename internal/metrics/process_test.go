@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeStat writes a /proc/<pid>/stat file for a process with the given
+// user and system ticks.
+func writeStat(t *testing.T, root string, pid int, utime, stime uint64) {
+	t.Helper()
+	dir := filepath.Join(root, fmt.Sprint(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (my app) R 1 %d %d 0 -1 0 0 0 0 0 %d %d 0 0 20 0 4 0 1000 0 256 0\n", pid, pid, pid, utime, stime)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestScanFresh checks that CPU% is measured against the previous scan
+// without waiting, and that a scan younger than the window is reused.
+func TestScanFresh(t *testing.T) {
+	root := t.TempDir()
+	s := newProcessScanner(root)
+	window := 250 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		utime   uint64
+		stime   uint64
+		age     time.Duration
+		wantCPU float64
+	}{
+		{name: "first scan", utime: 100, stime: 50, wantCPU: 0},
+		{name: "against the previous scan", utime: 130, stime: 70, age: 2 * time.Second, wantCPU: 25},
+		{name: "reused inside the window", utime: 500, stime: 500, age: 0, wantCPU: 25},
+		{name: "after the window", utime: 180, stime: 70, age: time.Second, wantCPU: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeStat(t, root, 42, tt.utime, tt.stime)
+			s.prevTime = s.prevTime.Add(-tt.age)
+
+			start := time.Now()
+			samples, err := s.scanFresh(window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed >= window {
+				t.Errorf("scanFresh took %s, want no wait", elapsed)
+			}
+			if len(samples) != 1 {
+				t.Fatalf("got %d samples, want 1", len(samples))
+			}
+			if got := samples[0].cpuPercent; got < tt.wantCPU-1 || got > tt.wantCPU+1 {
+				t.Errorf("cpuPercent = %.2f, want %.2f", got, tt.wantCPU)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// SnapshotSamples returns every metric in snap as a sample: the typed
+// fields under fixed names, with per-target metrics labelled by their
+// target, followed by snap.Samples. Declarative rules match against this
+// list, so the names here are part of the config format.
+func SnapshotSamples(snap MetricsSnapshot) []Sample {
+	var samples []Sample
+	add := func(name string, value float64, labels ...string) {
+		sample := Sample{Name: name, Value: value}
+		if len(labels) > 0 {
+			sample.Labels = make(map[string]string, len(labels)/2)
+			for i := 0; i+1 < len(labels); i += 2 {
+				sample.Labels[labels[i]] = labels[i+1]
+			}
+		}
+		samples = append(samples, sample)
+	}
+
+	add("cpu_usage_percent", snap.CPUUsagePercent)
+	add("cpu_usage_incl_iowait_percent", snap.CPUUsagePercent+snap.CPUTimes.IOWaitPercent)
+	add("cpu_user_percent", snap.CPUTimes.UserPercent)
+	add("cpu_nice_percent", snap.CPUTimes.NicePercent)
+	add("cpu_system_percent", snap.CPUTimes.SystemPercent)
+	add("cpu_idle_percent", snap.CPUTimes.IdlePercent)
+	add("cpu_iowait_percent", snap.CPUTimes.IOWaitPercent)
+	add("cpu_irq_percent", snap.CPUTimes.IRQPercent)
+	add("cpu_softirq_percent", snap.CPUTimes.SoftIRQPercent)
+	add("cpu_steal_percent", snap.CPUTimes.StealPercent)
+	for _, core := range snap.CPUCores {
+		add("cpu_core_usage_percent", core.UsagePercent, "core", core.Name)
+		add("cpu_core_iowait_percent", core.Times.IOWaitPercent, "core", core.Name)
+		add("cpu_core_steal_percent", core.Times.StealPercent, "core", core.Name)
+	}
+
+	load := snap.Load
+	add("load1", load.Load1)
+	add("load5", load.Load5)
+	add("load15", load.Load15)
+	if len(snap.CPUCores) > 0 {
+		add("load1_per_core", load.Load1PerCore)
+		add("load5_per_core", load.Load5PerCore)
+	}
+	add("tasks_running", float64(load.TasksRunning))
+	add("tasks_total", float64(load.TasksTotal))
+	add("procs_running", float64(load.ProcsRunning))
+	add("procs_blocked", float64(load.ProcsBlocked))
+	add("context_switches_ps", load.ContextSwitchesPS)
+	add("interrupts_ps", load.InterruptsPS)
+	add("forks_ps", load.ForksPS)
+
+	tables := snap.KernelTables
+	add("file_handles", float64(tables.FileHandles))
+	add("file_handles_percent", tables.FileHandlesPercent)
+	add("conntrack", float64(tables.Conntrack))
+	add("conntrack_percent", tables.ConntrackPercent)
+	add("pids", float64(tables.PIDs))
+	add("pids_percent", tables.PIDsPercent)
+	add("inotify_watches_percent", tables.InotifyWatchesPercent)
+	add("inotify_instances_percent", tables.InotifyInstancesPercent)
+
+	add("mem_used_percent", snap.MemUsedPercent)
+	add("mem_used_bytes", float64(snap.MemUsedBytes))
+	add("mem_total_bytes", float64(snap.MemTotalBytes))
+	add("mem_cached_bytes", float64(snap.MemBreakdown.CachedBytes))
+	add("mem_dirty_bytes", float64(snap.MemBreakdown.DirtyBytes))
+	add("mem_slab_bytes", float64(snap.MemBreakdown.SlabBytes))
+	add("swap_used_percent", snap.SwapUsedPercent)
+	add("swap_used_bytes", float64(snap.SwapUsedBytes))
+	add("swap_in_pages_ps", snap.SwapInPagesPS)
+	add("swap_out_pages_ps", snap.SwapOutPagesPS)
+	add("page_faults_ps", snap.PageFaultsPS)
+	add("major_faults_ps", snap.MajorFaultsPS)
+	add("dirty_growth_bytes_ps", snap.DirtyGrowthBytesPS)
+	add("oom_kills", float64(snap.OOMKills))
+	add("oom_victims", float64(len(snap.OOMVictims)))
+
+	var kernelRules []string
+	kernelEvents := make(map[string]int)
+	for _, event := range snap.KernelEvents {
+		if kernelEvents[event.Rule] == 0 {
+			kernelRules = append(kernelRules, event.Rule)
+		}
+		kernelEvents[event.Rule]++
+	}
+	for _, rule := range kernelRules {
+		add("kernel_events", float64(kernelEvents[rule]), "rule", rule)
+	}
+
+	add("net_rx_mbps_total", snap.NetRxMbps)
+	add("net_tx_mbps_total", snap.NetTxMbps)
+	for _, iface := range snap.Interfaces {
+		add("net_rx_mbps", iface.RxMbps, "interface", iface.Name)
+		add("net_tx_mbps", iface.TxMbps, "interface", iface.Name)
+		add("net_rx_bytes_ps", iface.RxBytesPS, "interface", iface.Name)
+		add("net_tx_bytes_ps", iface.TxBytesPS, "interface", iface.Name)
+		add("net_rx_packets_ps", iface.RxPacketsPS, "interface", iface.Name)
+		add("net_tx_packets_ps", iface.TxPacketsPS, "interface", iface.Name)
+		add("net_rx_errors_ps", iface.RxErrorsPS, "interface", iface.Name)
+		add("net_tx_errors_ps", iface.TxErrorsPS, "interface", iface.Name)
+		add("net_rx_drops_ps", iface.RxDropsPS, "interface", iface.Name)
+		add("net_tx_drops_ps", iface.TxDropsPS, "interface", iface.Name)
+		add("net_packets_ps", iface.RxPacketsPS+iface.TxPacketsPS, "interface", iface.Name)
+		add("net_errors_ps", iface.RxErrorsPS+iface.TxErrorsPS, "interface", iface.Name)
+		add("net_drops_ps", iface.RxDropsPS+iface.TxDropsPS, "interface", iface.Name)
+		add("net_admin_up", boolValue(iface.AdminUp), "interface", iface.Name)
+		add("net_link_up", boolValue(iface.LinkUp), "interface", iface.Name)
+		add("net_carrier_changes", float64(iface.CarrierChanges), "interface", iface.Name)
+		add("net_speed_mbps", float64(iface.SpeedMbps), "interface", iface.Name)
+	}
+
+	sock := snap.Sockets
+	add("tcp_established", float64(sock.TCPEstablished))
+	add("tcp_syn_recv", float64(sock.TCPSynRecv))
+	add("tcp_time_wait", float64(sock.TCPTimeWait))
+	add("tcp_close_wait", float64(sock.TCPCloseWait))
+	add("tcp_orphans", float64(sock.TCPOrphans))
+	add("tcp_retrans_percent", sock.TCPRetransPercent)
+	add("listen_overflows_ps", sock.ListenOverflowsPS)
+	add("listen_drops_ps", sock.ListenDropsPS)
+	add("udp_in_errors_ps", sock.UDPInErrorsPS)
+	add("udp_rcvbuf_errors_ps", sock.UDPRcvbufErrorsPS)
+
+	if snap.Pressure.Available {
+		for _, res := range []struct {
+			name     string
+			resource PressureResource
+		}{{"cpu", snap.Pressure.CPU}, {"memory", snap.Pressure.Memory}, {"io", snap.Pressure.IO}} {
+			add("pressure_some_avg10", res.resource.Some.Avg10, "resource", res.name)
+			add("pressure_some_avg60", res.resource.Some.Avg60, "resource", res.name)
+			add("pressure_some_avg300", res.resource.Some.Avg300, "resource", res.name)
+			add("pressure_full_avg10", res.resource.Full.Avg10, "resource", res.name)
+			add("pressure_full_avg60", res.resource.Full.Avg60, "resource", res.name)
+			add("pressure_full_avg300", res.resource.Full.Avg300, "resource", res.name)
+		}
+	}
+
+	for _, disk := range snap.Disks {
+		add("disk_util_percent", disk.UtilPercent, "device", disk.Device)
+		add("disk_await_ms", disk.AwaitMs, "device", disk.Device)
+		add("disk_iops", disk.ReadIOPS+disk.WriteIOPS, "device", disk.Device)
+		add("disk_read_iops", disk.ReadIOPS, "device", disk.Device)
+		add("disk_write_iops", disk.WriteIOPS, "device", disk.Device)
+		add("disk_read_bytes_ps", disk.ReadBytesPS, "device", disk.Device)
+		add("disk_write_bytes_ps", disk.WriteBytesPS, "device", disk.Device)
+	}
+
+	for _, fs := range snap.Filesystems {
+		add("filesystem_used_percent", fs.UsedPercent, "mount", fs.MountPoint)
+		add("filesystem_free_bytes", float64(fs.FreeBytes), "mount", fs.MountPoint)
+		add("filesystem_inodes_used_percent", fs.InodesUsedPercent, "mount", fs.MountPoint)
+	}
+
+	for _, proc := range snap.WatchedProcesses {
+		add("process_count", float64(proc.Count), "process", proc.Name)
+		add("process_restarted", boolValue(proc.Restarted), "process", proc.Name)
+		add("process_cpu_percent", proc.CPUPercent, "process", proc.Name)
+		add("process_rss_bytes", float64(proc.RSSBytes), "process", proc.Name)
+		add("process_open_fds", float64(proc.OpenFDs), "process", proc.Name)
+		add("process_threads", float64(proc.Threads), "process", proc.Name)
+	}
+
+	for _, cg := range snap.Cgroups {
+		add("cgroup_cpu_percent", cg.CPUUsagePercent, "cgroup", cg.Path)
+		add("cgroup_memory_current_bytes", float64(cg.MemoryCurrentBytes), "cgroup", cg.Path)
+		add("cgroup_memory_used_percent", cg.MemoryUsedPercent, "cgroup", cg.Path)
+		add("cgroup_oom_kills", float64(cg.NewOOMKills), "cgroup", cg.Path)
+		add("cgroup_pids_used_percent", cg.PidsUsedPercent, "cgroup", cg.Path)
+	}
+
+	for _, sensor := range snap.Thermal.Sensors {
+		add("temperature_celsius", sensor.TempCelsius, "sensor", sensor.Name)
+		if sensor.CritCelsius > 0 {
+			add("temperature_crit_headroom_celsius", sensor.CritCelsius-sensor.TempCelsius, "sensor", sensor.Name)
+		}
+	}
+	add("throttle_events", float64(snap.Thermal.CoreThrottles+snap.Thermal.PackageThrottles))
+
+	for _, probe := range snap.Probes {
+		add("probe_up", boolValue(probe.Up), "probe", probe.Name)
+		add("probe_latency_ms", probe.LatencyMs, "probe", probe.Name)
+		if !probe.CertExpiry.IsZero() {
+			add("probe_cert_days", probe.CertDays, "probe", probe.Name)
+		}
+	}
+
+	for _, m := range snap.LogMatches {
+		add("log_matches", float64(m.Matches), "file", m.File, "rule", m.Rule)
+		add("log_matches_ps", m.MatchesPS, "file", m.File, "rule", m.Rule)
+		add("log_critical", boolValue(m.Critical), "file", m.File, "rule", m.Rule)
+	}
+
+	for _, tf := range snap.Textfiles {
+		add("textfile_age_sec", tf.AgeSec, "file", tf.Name)
+		add("textfile_error", boolValue(tf.Error != ""), "file", tf.Name)
+	}
+
+	return append(samples, snap.Samples...)
+}
+
+// builtinSeries holds the names SnapshotSamples uses for the typed fields,
+// taken from a snapshot with one of every target and every optional field
+// set. Sources reporting samples of their own must not reuse them, or
+// rules on a built-in series would match their samples too.
+var builtinSeries = func() map[string]bool {
+	snap := MetricsSnapshot{
+		CPUCores:         []CPUCoreStats{{}},
+		KernelEvents:     []KernelEvent{{}},
+		Interfaces:       []InterfaceStats{{}},
+		Pressure:         PressureStats{Available: true},
+		Disks:            []DiskStats{{}},
+		Filesystems:      []FilesystemStats{{}},
+		WatchedProcesses: []WatchedProcessStats{{}},
+		Cgroups:          []CgroupStats{{}},
+		Thermal:          ThermalStats{Sensors: []TemperatureSensor{{CritCelsius: 1}}},
+		Probes:           []ProbeResult{{CertExpiry: time.Unix(1, 0)}},
+		LogMatches:       []LogMatchStats{{}},
+		Textfiles:        []TextfileStats{{}},
+	}
+
+	names := make(map[string]bool)
+	for _, sample := range SnapshotSamples(snap) {
+		names[sample.Name] = true
+	}
+	return names
+}()
+
+// CheckSampleNames returns an error for the first sample named like a
+// built-in series.
+func CheckSampleNames(samples []Sample) error {
+	for _, sample := range samples {
+		if builtinSeries[sample.Name] {
+			return fmt.Errorf("sample %s shadows a built-in series", sample.Name)
+		}
+	}
+	return nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
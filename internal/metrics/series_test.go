@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBuiltinSeries checks that builtinSeries lists every name
+// SnapshotSamples uses for a populated snapshot, and nothing else, so no
+// plugin sample can shadow one.
+func TestBuiltinSeries(t *testing.T) {
+	snap := MetricsSnapshot{
+		CPUCores:         []CPUCoreStats{{Name: "cpu0"}},
+		OOMVictims:       []OOMVictim{{PID: 1, Name: "app"}},
+		KernelEvents:     []KernelEvent{{Rule: "hung"}},
+		Interfaces:       []InterfaceStats{{Name: "eth0"}},
+		Pressure:         PressureStats{Available: true},
+		Disks:            []DiskStats{{Device: "sda"}},
+		Filesystems:      []FilesystemStats{{MountPoint: "/"}},
+		WatchedProcesses: []WatchedProcessStats{{Name: "nginx"}},
+		Cgroups:          []CgroupStats{{Path: "system.slice"}},
+		Thermal:          ThermalStats{Sensors: []TemperatureSensor{{Name: "pkg", CritCelsius: 100}}},
+		Probes:           []ProbeResult{{Name: "web", CertExpiry: time.Unix(1700000000, 0)}},
+		LogMatches:       []LogMatchStats{{File: "app", Rule: "error"}},
+		Textfiles:        []TextfileStats{{Name: "backup"}},
+	}
+
+	seen := make(map[string]bool)
+	for _, sample := range SnapshotSamples(snap) {
+		seen[sample.Name] = true
+		if !builtinSeries[sample.Name] {
+			t.Errorf("series %s missing from builtinSeries", sample.Name)
+		}
+	}
+	for name := range builtinSeries {
+		if !seen[name] {
+			t.Errorf("builtinSeries lists %s, which SnapshotSamples does not report", name)
+		}
+	}
+
+	if err := CheckSampleNames([]Sample{{Name: "queue_depth"}}); err != nil {
+		t.Errorf("CheckSampleNames(queue_depth) = %v, want nil", err)
+	}
+	if err := CheckSampleNames([]Sample{{Name: "queue_depth"}, {Name: "mem_used_percent"}}); err == nil {
+		t.Error("CheckSampleNames(mem_used_percent) = nil, want an error")
+	}
+}
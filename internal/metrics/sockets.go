@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type socketSource struct {
+	prevSockets    map[string]uint64
+	prevSocketTime time.Time
+}
+
+func (s *socketSource) Name() string { return "sockets" }
+
+func (s *socketSource) Init() error { return nil }
+
+func (s *socketSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	sockets, err := s.collectSockets(snap.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Sockets = sockets
+	return nil, nil
+}
+
+// TCP states as encoded in the st column of /proc/net/tcp.
+const (
+	tcpEstablished = 0x01
+	tcpSynSent     = 0x02
+	tcpSynRecv     = 0x03
+	tcpFinWait1    = 0x04
+	tcpFinWait2    = 0x05
+	tcpTimeWait    = 0x06
+	tcpCloseWait   = 0x08
+	tcpLastAck     = 0x09
+	tcpListen      = 0x0A
+	tcpClosing     = 0x0B
+)
+
+// collectSockets counts TCP sockets by state and reads socket totals and
+// protocol counters. Hosts without IPv6 simply have no tcp6 table.
+func (s *socketSource) collectSockets(now time.Time) (SocketStats, error) {
+	var stats SocketStats
+
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := countTCPStates(table, &stats); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return SocketStats{}, err
+		}
+	}
+
+	for _, file := range []string{"/proc/net/sockstat", "/proc/net/sockstat6"} {
+		if err := readSockstat(file, &stats); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return SocketStats{}, err
+		}
+	}
+
+	counters := make(map[string]uint64)
+	for _, file := range []string{"/proc/net/snmp", "/proc/net/netstat"} {
+		if err := readProtoCounters(file, counters); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return SocketStats{}, err
+		}
+	}
+
+	prev := s.prevSockets
+	prevTime := s.prevSocketTime
+	s.prevSockets = counters
+	s.prevSocketTime = now
+
+	if prevTime.IsZero() {
+		return stats, nil
+	}
+
+	deltaTime := now.Sub(prevTime).Seconds()
+	if deltaTime <= 0 {
+		deltaTime = 1.0
+	}
+
+	rate := func(key string) float64 {
+		curr, ok := counters[key]
+		old, seen := prev[key]
+		if !ok || !seen || curr < old {
+			return 0.0
+		}
+		return float64(curr-old) / deltaTime
+	}
+
+	stats.TCPActiveOpensPS = rate("Tcp.ActiveOpens")
+	stats.TCPPassiveOpensPS = rate("Tcp.PassiveOpens")
+	stats.TCPAttemptFailsPS = rate("Tcp.AttemptFails")
+	stats.TCPEstabResetsPS = rate("Tcp.EstabResets")
+	stats.TCPOutSegsPS = rate("Tcp.OutSegs")
+	stats.TCPRetransSegsPS = rate("Tcp.RetransSegs")
+	if stats.TCPOutSegsPS > 0 {
+		stats.TCPRetransPercent = stats.TCPRetransSegsPS / stats.TCPOutSegsPS * 100.0
+	}
+	stats.ListenOverflowsPS = rate("TcpExt.ListenOverflows")
+	stats.ListenDropsPS = rate("TcpExt.ListenDrops")
+	stats.SyncookiesSentPS = rate("TcpExt.SyncookiesSent")
+	stats.UDPInErrorsPS = rate("Udp.InErrors")
+	stats.UDPRcvbufErrorsPS = rate("Udp.RcvbufErrors")
+	stats.UDPSndbufErrorsPS = rate("Udp.SndbufErrors")
+
+	return stats, nil
+}
+
+// countTCPStates tallies the st column of a /proc/net/tcp style table. Only
+// that column is parsed, which keeps hosts with many connections cheap.
+func countTCPStates(path string, stats *SocketStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			continue
+		}
+
+		switch state {
+		case tcpEstablished:
+			stats.TCPEstablished++
+		case tcpSynSent:
+			stats.TCPSynSent++
+		case tcpSynRecv:
+			stats.TCPSynRecv++
+		case tcpFinWait1, tcpFinWait2:
+			stats.TCPFinWait++
+		case tcpTimeWait:
+			stats.TCPTimeWait++
+		case tcpCloseWait:
+			stats.TCPCloseWait++
+		case tcpLastAck:
+			stats.TCPLastAck++
+		case tcpListen:
+			stats.TCPListen++
+		case tcpClosing:
+			stats.TCPClosing++
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readSockstat adds the kernel's socket totals. IPv4 and IPv6 in-use counts
+// are summed; orphans, TIME_WAIT buckets and memory are shared.
+func readSockstat(path string, stats *SocketStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		proto, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		values := make(map[string]uint64)
+		for i := 0; i+1 < len(fields); i += 2 {
+			values[fields[i]], _ = strconv.ParseUint(fields[i+1], 10, 64)
+		}
+
+		switch proto {
+		case "sockets":
+			stats.SocketsUsed = values["used"]
+		case "TCP":
+			stats.TCPInUse += values["inuse"]
+			stats.TCPOrphans = values["orphan"]
+			stats.TCPTimeWaitBuckets = values["tw"]
+			stats.TCPAlloc = values["alloc"]
+			stats.TCPMemPages = values["mem"]
+		case "TCP6":
+			stats.TCPInUse += values["inuse"]
+		case "UDP", "UDP6":
+			stats.UDPInUse += values["inuse"]
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readProtoCounters parses the header/value line pairs of /proc/net/snmp
+// and /proc/net/netstat into "Proto.Counter" keys.
+func readProtoCounters(path string, counters map[string]uint64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		header := scanner.Text()
+		if !scanner.Scan() {
+			break
+		}
+		proto, names, ok := strings.Cut(header, ":")
+		valProto, values, ok2 := strings.Cut(scanner.Text(), ":")
+		if !ok || !ok2 || proto != valProto {
+			return fmt.Errorf("%s: mismatched lines for %s", path, proto)
+		}
+
+		nameFields := strings.Fields(names)
+		valueFields := strings.Fields(values)
+		for i := 0; i < len(nameFields) && i < len(valueFields); i++ {
+			// A few fields, such as Tcp MaxConn, are signed; they are not
+			// counters and can be skipped.
+			if val, err := strconv.ParseUint(valueFields[i], 10, 64); err == nil {
+				counters[proto+"."+nameFields[i]] = val
+			}
+		}
+	}
+
+	return scanner.Err()
+}
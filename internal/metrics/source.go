@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"system-sentinel/internal/config"
+)
+
+// Sample is one labelled value from a source, for metrics that have no
+// typed field on MetricsSnapshot.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Source is one family of metrics. Init runs once at startup. Collect fills
+// the source's typed fields on snap, if it has any, and returns labelled
+// samples for everything else. Sources run in registration order, so a
+// source may read fields filled by the ones before it.
+type Source interface {
+	Name() string
+	Init() error
+	Collect(snap *MetricsSnapshot) ([]Sample, error)
+}
+
+// Registry holds the enabled sources in collection order.
+type Registry struct {
+	sources []Source
+	procs   *processSource
+}
+
+// NewRegistry registers the built-in sources enabled in cfg. Sources are
+// not initialized until Init is called.
+func NewRegistry(cfg *config.Config) *Registry {
+	scanner := newProcessScanner("/proc")
+	procs := &processSource{
+		procs:     scanner,
+		watches:   newProcessWatches(cfg.Processes),
+		topWindow: time.Duration(cfg.TopProcesses.WindowMs) * time.Millisecond,
+	}
+	if cfg.TopProcesses.Enabled {
+		procs.topN = cfg.TopProcesses.Count
+		procs.topEverySample = cfg.TopProcesses.EverySample
+	}
+
+	r := &Registry{procs: procs}
+	src := cfg.Sources
+
+	builtins := []struct {
+		enabled bool
+		source  Source
+	}{
+		{src.CPU.IsEnabled(), &cpuSource{}},
+		{src.Load.IsEnabled(), &loadSource{}},
+		{src.KernelTables.IsEnabled(), &tableSource{procs: scanner, scanInotify: cfg.KernelTables.ScanInotify}},
+		{src.Memory.IsEnabled(), &memorySource{}},
+		{src.Network.IsEnabled(), &networkSource{interfaces: cfg.NetworkInterfaces()}},
+		{src.Sockets.IsEnabled(), &socketSource{}},
+		{src.Pressure.IsEnabled(), &pressureSource{}},
+		{src.Disk.IsEnabled(), &diskSource{disks: cfg.Disks}},
+		{src.Filesystem.IsEnabled(), &filesystemSource{filesystems: cfg.Filesystems}},
+		{src.Thermal.IsEnabled(), &thermalSource{sysRoot: cfg.Thermal.SysfsRoot}},
+		{src.Cgroup.IsEnabled(), &cgroupSource{cgroupRoot: cfg.Cgroups.Root, cgroupPatterns: cfg.Cgroups.Include}},
+		{src.Processes.IsEnabled(), procs},
+		{src.KernelLog.IsEnabled(), newKernelLogSource(cfg.KernelLog)},
+		{src.Textfile.IsEnabled(), &textfileSource{dir: cfg.Textfile.Dir}},
+		{src.LogFiles.IsEnabled() && len(cfg.LogFiles) > 0, newLogFileSource(cfg.LogFiles)},
+	}
+	for _, b := range builtins {
+		if b.enabled {
+			r.Register(b.source)
+		}
+	}
+
+	return r
+}
+
+// Register appends a source to the collection order.
+func (r *Registry) Register(source Source) {
+	r.sources = append(r.sources, source)
+}
+
+// Init initializes every source. Sources that fail are dropped so the rest
+// keep running; their errors are returned.
+func (r *Registry) Init() []error {
+	var errs []error
+	sources := r.sources[:0]
+	for _, source := range r.sources {
+		if err := source.Init(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		sources = append(sources, source)
+	}
+	r.sources = sources
+	return errs
+}
+
+func (r *Registry) Sources() []Source {
+	return r.sources
+}
+
+// TopProcesses returns the busiest processes by CPU and by RSS, whether or
+// not the processes source is enabled.
+func (r *Registry) TopProcesses() (*TopProcesses, error) {
+	return r.procs.TopProcesses()
+}
+
+// counterDelta returns how much a kernel counter grew from old to curr, or
+// 0 when it went backwards because the counter was reset, as when an
+// interface is recreated or a 32-bit counter wraps.
+func counterDelta(curr, old uint64) uint64 {
+	if curr < old {
+		return 0
+	}
+	return curr - old
+}
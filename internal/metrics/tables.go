@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tableSource reports kernel table usage.
+type tableSource struct {
+	procs       *processScanner
+	scanInotify bool
+}
+
+func (s *tableSource) Name() string { return "kernel_tables" }
+
+func (s *tableSource) Init() error { return nil }
+
+func (s *tableSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	tables, err := s.collectKernelTables()
+	if err != nil {
+		return nil, err
+	}
+	snap.KernelTables = tables
+	return nil, nil
+}
+
+// collectKernelTables reports usage of kernel tables that can run out
+// independently of CPU and memory. Each limit that cannot be read, such as
+// conntrack without the nf_conntrack module, is left at 0.
+func (s *tableSource) collectKernelTables() (KernelTableStats, error) {
+	var stats KernelTableStats
+
+	// file-nr holds allocated handles, free allocated handles (always 0
+	// since 2.6) and the maximum.
+	if data, err := os.ReadFile("/proc/sys/fs/file-nr"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 3 {
+			allocated, _ := strconv.ParseUint(fields[0], 10, 64)
+			free, _ := strconv.ParseUint(fields[1], 10, 64)
+			stats.FileHandlesMax, _ = strconv.ParseUint(fields[2], 10, 64)
+			if allocated >= free {
+				stats.FileHandles = allocated - free
+			}
+		}
+	}
+	stats.FileHandlesPercent = tablePercent(stats.FileHandles, stats.FileHandlesMax)
+
+	stats.Conntrack, _ = readSysUint("/proc/sys/net/netfilter/nf_conntrack_count")
+	stats.ConntrackMax, _ = readSysUint("/proc/sys/net/netfilter/nf_conntrack_max")
+	stats.ConntrackPercent = tablePercent(stats.Conntrack, stats.ConntrackMax)
+
+	// Every thread takes a PID, so the task count from /proc/loadavg is
+	// what counts against pid_max. It is read here rather than taken from
+	// the load source, which may be disabled.
+	stats.PIDs = readTaskCount()
+	stats.PIDMax, _ = readSysUint("/proc/sys/kernel/pid_max")
+	stats.PIDsPercent = tablePercent(stats.PIDs, stats.PIDMax)
+
+	if s.scanInotify {
+		stats.InotifyMaxUserWatches, _ = readSysUint("/proc/sys/fs/inotify/max_user_watches")
+		stats.InotifyMaxUserInstances, _ = readSysUint("/proc/sys/fs/inotify/max_user_instances")
+		stats.InotifyWatches, stats.InotifyInstances = s.scanInotifyUsage()
+		stats.InotifyWatchesPercent = tablePercent(stats.InotifyWatches, stats.InotifyMaxUserWatches)
+		stats.InotifyInstancesPercent = tablePercent(stats.InotifyInstances, stats.InotifyMaxUserInstances)
+	}
+
+	return stats, nil
+}
+
+// readTaskCount returns the total from the "running/total" field of
+// /proc/loadavg, or 0 when it cannot be read.
+func readTaskCount() uint64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 4 {
+		return 0
+	}
+	_, total, _ := strings.Cut(fields[3], "/")
+	tasks, _ := strconv.ParseUint(total, 10, 64)
+	return tasks
+}
+
+// scanInotifyUsage walks every process's file descriptors to count inotify
+// instances and watches per user, since the kernel only exposes per-user
+// limits. It returns the highest counts of any single user.
+func (s *tableSource) scanInotifyUsage() (uint64, uint64) {
+	procRoot := s.procs.procRoot
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0, 0
+	}
+
+	watches := make(map[int]uint64)
+	instances := make(map[int]uint64)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		dir := filepath.Join(procRoot, entry.Name())
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue
+		}
+
+		uid := -1
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || target != "anon_inode:inotify" {
+				continue
+			}
+			if uid < 0 {
+				uid = s.procs.uid(pid)
+			}
+			instances[uid]++
+
+			data, err := os.ReadFile(filepath.Join(dir, "fdinfo", fd.Name()))
+			if err != nil {
+				continue
+			}
+			watches[uid] += uint64(strings.Count(string(data), "inotify wd:"))
+		}
+	}
+
+	var maxWatches, maxInstances uint64
+	for _, n := range watches {
+		if n > maxWatches {
+			maxWatches = n
+		}
+	}
+	for _, n := range instances {
+		if n > maxInstances {
+			maxInstances = n
+		}
+	}
+
+	return maxWatches, maxInstances
+}
+
+func tablePercent(used, limit uint64) float64 {
+	if limit == 0 {
+		return 0.0
+	}
+	return float64(used) / float64(limit) * 100.0
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// textfileSource reads the *.prom files that cron jobs and other tools
+// write into a directory, in the Prometheus text format. Files should be
+// written to a temporary name and renamed into place so a half-written
+// file is never read. Each file is parsed again only when its size or
+// modification time changes.
+type textfileSource struct {
+	dir   string
+	files map[string]textfileEntry
+}
+
+type textfileEntry struct {
+	modTime time.Time
+	size    int64
+	samples []Sample
+	err     error
+}
+
+func (s *textfileSource) Name() string { return "textfile" }
+
+func (s *textfileSource) Init() error {
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.dir)
+	}
+	s.files = make(map[string]textfileEntry)
+	return nil
+}
+
+func (s *textfileSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.prom"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var samples []Sample
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			// Removed or replaced between the glob and the stat.
+			continue
+		}
+		name := filepath.Base(path)
+		seen[name] = true
+
+		entry, ok := s.files[name]
+		if !ok || !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+			entry = readTextfile(path, name, info)
+			s.files[name] = entry
+		}
+
+		stats := TextfileStats{
+			Name:    name,
+			ModTime: entry.modTime,
+			AgeSec:  snap.Timestamp.Sub(entry.modTime).Seconds(),
+			Samples: len(entry.samples),
+		}
+		if entry.err != nil {
+			stats.Error = entry.err.Error()
+		}
+		snap.Textfiles = append(snap.Textfiles, stats)
+		samples = append(samples, entry.samples...)
+	}
+
+	for name := range s.files {
+		if !seen[name] {
+			delete(s.files, name)
+		}
+	}
+
+	return samples, nil
+}
+
+// readTextfile parses one file and labels its samples textfile=<name>.
+func readTextfile(path, name string, info os.FileInfo) textfileEntry {
+	entry := textfileEntry{modTime: info.ModTime(), size: info.Size()}
+
+	file, err := os.Open(path)
+	if err != nil {
+		entry.err = err
+		return entry
+	}
+	defer file.Close()
+
+	samples, err := ParsePrometheusText(file)
+	if err == nil {
+		err = CheckSampleNames(samples)
+	}
+	if err != nil {
+		entry.err = err
+		return entry
+	}
+	for i := range samples {
+		if samples[i].Labels == nil {
+			samples[i].Labels = make(map[string]string, 1)
+		}
+		samples[i].Labels["textfile"] = name
+	}
+	entry.samples = samples
+	return entry
+}
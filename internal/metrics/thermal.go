@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type thermalSource struct {
+	sysRoot      string
+	prevThrottle throttleCounters
+}
+
+func (s *thermalSource) Name() string { return "thermal" }
+
+func (s *thermalSource) Init() error { return nil }
+
+func (s *thermalSource) Collect(snap *MetricsSnapshot) ([]Sample, error) {
+	thermal, err := s.collectThermal()
+	if err != nil {
+		return nil, err
+	}
+	snap.Thermal = thermal
+	return nil, nil
+}
+
+type throttleCounters struct {
+	core map[string]uint64
+	pkg  map[string]uint64
+}
+
+// collectThermal reads hwmon temperature sensors, thermal zones and the
+// per-CPU throttle counters under the configured sysfs root. Machines
+// without them, such as most VMs and containers, report nothing.
+func (s *thermalSource) collectThermal() (ThermalStats, error) {
+	var stats ThermalStats
+
+	seen := make(map[string]int)
+	add := func(sensor TemperatureSensor) {
+		// Identical chips (two NVMe drives, say) share names; number the
+		// repeats so each sensor keeps a distinct name.
+		seen[sensor.Name]++
+		if n := seen[sensor.Name]; n > 1 {
+			sensor.Name += "#" + strconv.Itoa(n)
+		}
+		stats.Sensors = append(stats.Sensors, sensor)
+	}
+
+	for _, sensor := range s.readHwmon() {
+		add(sensor)
+	}
+	for _, sensor := range s.readThermalZones() {
+		add(sensor)
+	}
+
+	s.readThrottle(&stats)
+
+	return stats, nil
+}
+
+// readHwmon reports every temp*_input under /sys/class/hwmon, named
+// "<chip>/<label>" or "<chip>/tempN" when the driver exports no label.
+func (s *thermalSource) readHwmon() []TemperatureSensor {
+	chips, _ := filepath.Glob(filepath.Join(s.sysRoot, "class/hwmon/hwmon*"))
+	sort.Slice(chips, func(i, j int) bool { return sysIndex(chips[i]) < sysIndex(chips[j]) })
+
+	var sensors []TemperatureSensor
+	for _, chip := range chips {
+		chipName := readSysString(filepath.Join(chip, "name"))
+		if chipName == "" {
+			chipName = filepath.Base(chip)
+		}
+
+		inputs, _ := filepath.Glob(filepath.Join(chip, "temp*_input"))
+		sort.Slice(inputs, func(i, j int) bool { return sysIndex(inputs[i]) < sysIndex(inputs[j]) })
+		for _, input := range inputs {
+			temp, ok := readMilliCelsius(input)
+			if !ok {
+				continue
+			}
+			prefix := strings.TrimSuffix(input, "_input")
+			label := readSysString(prefix + "_label")
+			if label == "" {
+				label = filepath.Base(prefix)
+			}
+
+			sensor := TemperatureSensor{
+				Name:        chipName + "/" + label,
+				Source:      "hwmon",
+				TempCelsius: temp,
+			}
+			sensor.MaxCelsius, _ = readMilliCelsius(prefix + "_max")
+			sensor.CritCelsius, _ = readMilliCelsius(prefix + "_crit")
+			sensors = append(sensors, sensor)
+		}
+	}
+
+	return sensors
+}
+
+// readThermalZones reports /sys/class/thermal/thermal_zone*, named after the
+// zone type (x86_pkg_temp, acpitz, ...). The critical trip point, if any,
+// fills CritCelsius.
+func (s *thermalSource) readThermalZones() []TemperatureSensor {
+	zones, _ := filepath.Glob(filepath.Join(s.sysRoot, "class/thermal/thermal_zone*"))
+	sort.Slice(zones, func(i, j int) bool { return sysIndex(zones[i]) < sysIndex(zones[j]) })
+
+	var sensors []TemperatureSensor
+	for _, zone := range zones {
+		temp, ok := readMilliCelsius(filepath.Join(zone, "temp"))
+		if !ok {
+			continue
+		}
+		name := readSysString(filepath.Join(zone, "type"))
+		if name == "" {
+			name = filepath.Base(zone)
+		}
+
+		sensor := TemperatureSensor{
+			Name:        name,
+			Source:      "thermal_zone",
+			TempCelsius: temp,
+		}
+		trips, _ := filepath.Glob(filepath.Join(zone, "trip_point_*_type"))
+		for _, trip := range trips {
+			if readSysString(trip) == "critical" {
+				sensor.CritCelsius, _ = readMilliCelsius(strings.TrimSuffix(trip, "_type") + "_temp")
+				break
+			}
+		}
+		sensors = append(sensors, sensor)
+	}
+
+	return sensors
+}
+
+// readThrottle turns the cumulative thermal_throttle counters into events
+// since the previous sample. Package counters are exported by every CPU in
+// the package, so they are counted once per physical package.
+func (s *thermalSource) readThrottle(stats *ThermalStats) {
+	cpus, _ := filepath.Glob(filepath.Join(s.sysRoot, "devices/system/cpu/cpu[0-9]*"))
+	sort.Slice(cpus, func(i, j int) bool { return sysIndex(cpus[i]) < sysIndex(cpus[j]) })
+
+	current := throttleCounters{
+		core: make(map[string]uint64),
+		pkg:  make(map[string]uint64),
+	}
+	prev := s.prevThrottle
+
+	for _, cpu := range cpus {
+		name := filepath.Base(cpu)
+		dir := filepath.Join(cpu, "thermal_throttle")
+
+		if count, err := readSysUint(filepath.Join(dir, "core_throttle_count")); err == nil {
+			current.core[name] = count
+			if old, ok := prev.core[name]; ok && count > old {
+				stats.CoreThrottles += count - old
+				stats.ThrottledCPUs = append(stats.ThrottledCPUs, name)
+			}
+		}
+
+		pkgID := readSysString(filepath.Join(cpu, "topology/physical_package_id"))
+		if _, done := current.pkg[pkgID]; done {
+			continue
+		}
+		if count, err := readSysUint(filepath.Join(dir, "package_throttle_count")); err == nil {
+			current.pkg[pkgID] = count
+			if old, ok := prev.pkg[pkgID]; ok && count > old {
+				stats.PackageThrottles += count - old
+			}
+		}
+	}
+
+	s.prevThrottle = current
+}
+
+func readMilliCelsius(path string) (float64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	val, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return float64(val) / 1000.0, true
+}
+
+func readSysString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// sysIndex extracts the first number in a sysfs entry name so hwmon10 sorts
+// after hwmon9 and temp10_input after temp9_input.
+func sysIndex(path string) int {
+	name := filepath.Base(path)
+	start := strings.IndexAny(name, "0123456789")
+	if start < 0 {
+		return -1
+	}
+	end := start
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(name[start:end])
+	return n
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSysFile writes content under root, creating parent directories, the
+// way sysfs would expose it.
+func writeSysFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThermalSensors(t *testing.T) {
+	root := t.TempDir()
+
+	writeSysFile(t, root, "class/hwmon/hwmon0/name", "coretemp")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_input", "45000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_label", "Package id 0")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_max", "80000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp1_crit", "100000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp2_input", "41500")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp10_input", "39000")
+	writeSysFile(t, root, "class/hwmon/hwmon0/temp3_input", "not a number")
+	writeSysFile(t, root, "class/hwmon/hwmon1/name", "nvme")
+	writeSysFile(t, root, "class/hwmon/hwmon1/temp1_input", "35000")
+	writeSysFile(t, root, "class/hwmon/hwmon2/name", "nvme")
+	writeSysFile(t, root, "class/hwmon/hwmon2/temp1_input", "36000")
+
+	writeSysFile(t, root, "class/thermal/thermal_zone0/type", "acpitz")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/temp", "27800")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/trip_point_0_type", "passive")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/trip_point_0_temp", "90000")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/trip_point_1_type", "critical")
+	writeSysFile(t, root, "class/thermal/thermal_zone0/trip_point_1_temp", "105000")
+	writeSysFile(t, root, "class/thermal/thermal_zone1/temp", "30000")
+
+	s := &thermalSource{sysRoot: root}
+	stats, err := s.collectThermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TemperatureSensor{
+		{Name: "coretemp/Package id 0", Source: "hwmon", TempCelsius: 45, MaxCelsius: 80, CritCelsius: 100},
+		{Name: "coretemp/temp2", Source: "hwmon", TempCelsius: 41.5},
+		{Name: "coretemp/temp10", Source: "hwmon", TempCelsius: 39},
+		{Name: "nvme/temp1", Source: "hwmon", TempCelsius: 35},
+		{Name: "nvme/temp1#2", Source: "hwmon", TempCelsius: 36},
+		{Name: "acpitz", Source: "thermal_zone", TempCelsius: 27.8, CritCelsius: 105},
+		{Name: "thermal_zone1", Source: "thermal_zone", TempCelsius: 30},
+	}
+	if !reflect.DeepEqual(stats.Sensors, want) {
+		t.Errorf("sensors:\n got %+v\nwant %+v", stats.Sensors, want)
+	}
+}
+
+func TestThermalNoSensors(t *testing.T) {
+	s := &thermalSource{sysRoot: t.TempDir()}
+	stats, err := s.collectThermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats, ThermalStats{}) {
+		t.Errorf("got %+v, want no sensors or throttles", stats)
+	}
+}
+
+func TestThermalThrottleDeltas(t *testing.T) {
+	root := t.TempDir()
+	counters := func(cpu, pkg, core, pkgCount string) {
+		writeSysFile(t, root, "devices/system/cpu/"+cpu+"/topology/physical_package_id", pkg)
+		writeSysFile(t, root, "devices/system/cpu/"+cpu+"/thermal_throttle/core_throttle_count", core)
+		writeSysFile(t, root, "devices/system/cpu/"+cpu+"/thermal_throttle/package_throttle_count", pkgCount)
+	}
+
+	// cpu0 and cpu1 share package 0, cpu2 is alone in package 1.
+	counters("cpu0", "0", "10", "100")
+	counters("cpu1", "0", "5", "100")
+	counters("cpu2", "1", "0", "7")
+
+	s := &thermalSource{sysRoot: root}
+	first, err := s.collectThermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.CoreThrottles != 0 || first.PackageThrottles != 0 || first.ThrottledCPUs != nil {
+		t.Errorf("first sample: got %+v, want no events without a previous sample", first)
+	}
+
+	counters("cpu0", "0", "13", "104")
+	counters("cpu1", "0", "5", "104")
+	counters("cpu2", "1", "2", "9")
+
+	second, err := s.collectThermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.CoreThrottles != 5 {
+		t.Errorf("core throttles: got %d, want 5", second.CoreThrottles)
+	}
+	// The package counter is counted once per package, not once per CPU.
+	if second.PackageThrottles != 6 {
+		t.Errorf("package throttles: got %d, want 6", second.PackageThrottles)
+	}
+	if want := []string{"cpu0", "cpu2"}; !reflect.DeepEqual(second.ThrottledCPUs, want) {
+		t.Errorf("throttled cpus: got %v, want %v", second.ThrottledCPUs, want)
+	}
+
+	// A counter that went backwards, e.g. after a CPU was offlined and
+	// brought back, is not an event.
+	counters("cpu0", "0", "1", "104")
+
+	third, err := s.collectThermal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.CoreThrottles != 0 || third.PackageThrottles != 0 {
+		t.Errorf("third sample: got %+v, want no events", third)
+	}
+}
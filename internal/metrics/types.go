@@ -3,14 +3,343 @@ package metrics
 import "time"
 
 type MetricsSnapshot struct {
-	Timestamp       time.Time
-	CPUUsagePercent float64
-	MemUsedPercent  float64
-	MemUsedBytes    uint64
-	MemTotalBytes   uint64
-	NetInterface    string
-	NetRxBytesPS    float64
-	NetTxBytesPS    float64
-	NetRxMbps       float64
-	NetTxMbps       float64
+	Timestamp          time.Time
+	CPUUsagePercent    float64
+	CPUTimes           CPUTimes
+	CPUCores           []CPUCoreStats
+	Load               LoadStats
+	KernelTables       KernelTableStats
+	MemUsedPercent     float64
+	MemUsedBytes       uint64
+	MemTotalBytes      uint64
+	MemBreakdown       MemoryBreakdown
+	SwapTotalBytes     uint64
+	SwapUsedBytes      uint64
+	SwapUsedPercent    float64
+	SwapInPagesPS      float64
+	SwapOutPagesPS     float64
+	PageFaultsPS       float64
+	MajorFaultsPS      float64
+	DirtyGrowthBytesPS float64
+	OOMKills           uint64
+	OOMVictims         []OOMVictim
+	KernelEvents       []KernelEvent
+	LogMatches         []LogMatchStats
+	NetInterface       string
+	NetRxBytesPS       float64
+	NetTxBytesPS       float64
+	NetRxMbps          float64
+	NetTxMbps          float64
+	Interfaces         []InterfaceStats
+	Sockets            SocketStats
+	Pressure           PressureStats
+	Disks              []DiskStats
+	Filesystems        []FilesystemStats
+	WatchedProcesses   []WatchedProcessStats
+	Cgroups            []CgroupStats
+	Textfiles          []TextfileStats
+	Probes             []ProbeResult
+	Thermal            ThermalStats
+	Samples            []Sample
+	// TopProcesses is only set when a process scan ran for this sample. It
+	// is logged as a top-level field of the log entry rather than here.
+	TopProcesses *TopProcesses `json:"-"`
+}
+
+// LoadStats combines /proc/loadavg with the scheduler counters from
+// /proc/stat. Load1PerCore and Load5PerCore divide the 1- and 5-minute load
+// by the number of cores, and stay 0 when it is unknown.
+type LoadStats struct {
+	Load1             float64
+	Load5             float64
+	Load15            float64
+	Load1PerCore      float64
+	Load5PerCore      float64
+	TasksRunning      uint64
+	TasksTotal        uint64
+	ProcsRunning      uint64
+	ProcsBlocked      uint64
+	ContextSwitchesPS float64
+	InterruptsPS      float64
+	ForksPS           float64
+}
+
+type MemoryBreakdown struct {
+	CachedBytes       uint64
+	BuffersBytes      uint64
+	DirtyBytes        uint64
+	WritebackBytes    uint64
+	SlabBytes         uint64
+	SReclaimableBytes uint64
+	ShmemBytes        uint64
+	HugePagesTotal    uint64
+	HugePagesFree     uint64
+	HugePageSizeBytes uint64
+}
+
+// CPUTimes splits CPU time into the /proc/stat categories, each as a
+// percentage of the elapsed time between two samples.
+type CPUTimes struct {
+	UserPercent    float64
+	NicePercent    float64
+	SystemPercent  float64
+	IdlePercent    float64
+	IOWaitPercent  float64
+	IRQPercent     float64
+	SoftIRQPercent float64
+	StealPercent   float64
+}
+
+type CPUCoreStats struct {
+	Name         string
+	UsagePercent float64
+	Times        CPUTimes
+}
+
+type InterfaceStats struct {
+	Name            string
+	RxBytesPS       float64
+	TxBytesPS       float64
+	RxMbps          float64
+	TxMbps          float64
+	RxPacketsPS     float64
+	TxPacketsPS     float64
+	RxErrorsPS      float64
+	TxErrorsPS      float64
+	RxDropsPS       float64
+	TxDropsPS       float64
+	RxFifoErrorsPS  float64
+	TxFifoErrorsPS  float64
+	RxFrameErrorsPS float64
+	CollisionsPS    float64
+	OperState       string
+	AdminUp         bool
+	LinkUp          bool
+	SpeedMbps       int
+	Duplex          string
+	CarrierChanges  uint64
+}
+
+// PressureStats holds Linux pressure stall information. Available is false on
+// kernels without PSI support.
+type PressureStats struct {
+	Available bool
+	CPU       PressureResource
+	Memory    PressureResource
+	IO        PressureResource
+}
+
+type PressureResource struct {
+	Some PressureLine
+	Full PressureLine
+}
+
+// PressureLine holds the kernel's running averages (percent of wall time
+// stalled) and the stall time accumulated since the previous sample, in
+// microseconds per second.
+type PressureLine struct {
+	Avg10     float64
+	Avg60     float64
+	Avg300    float64
+	StallUsPS float64
+}
+
+type DiskStats struct {
+	Device       string
+	ReadBytesPS  float64
+	WriteBytesPS float64
+	ReadIOPS     float64
+	WriteIOPS    float64
+	AwaitMs      float64
+	UtilPercent  float64
+}
+
+type FilesystemStats struct {
+	MountPoint        string
+	Device            string
+	FSType            string
+	TotalBytes        uint64
+	UsedBytes         uint64
+	FreeBytes         uint64
+	UsedPercent       float64
+	InodesTotal       uint64
+	InodesUsed        uint64
+	InodesFree        uint64
+	InodesUsedPercent float64
+}
+
+// WatchedProcessStats aggregates every process matched by a configured
+// watch. MainPID is the longest-running match; Restarted is set when it
+// changed since the previous sample.
+type WatchedProcessStats struct {
+	Name       string
+	Count      int
+	PIDs       []int
+	MainPID    int
+	Restarted  bool
+	CPUPercent float64
+	RSSBytes   uint64
+	OpenFDs    int
+	Threads    int
+}
+
+// KernelTableStats holds usage of kernel tables against their limits.
+// Limits that could not be read are 0, as are their percentages. Inotify
+// usage is only scanned on request and reports the busiest single user,
+// since inotify limits apply per user.
+type KernelTableStats struct {
+	FileHandles             uint64
+	FileHandlesMax          uint64
+	FileHandlesPercent      float64
+	Conntrack               uint64
+	ConntrackMax            uint64
+	ConntrackPercent        float64
+	PIDs                    uint64
+	PIDMax                  uint64
+	PIDsPercent             float64
+	InotifyWatches          uint64
+	InotifyMaxUserWatches   uint64
+	InotifyWatchesPercent   float64
+	InotifyInstances        uint64
+	InotifyMaxUserInstances uint64
+	InotifyInstancesPercent float64
+}
+
+// SocketStats holds TCP socket counts by state (IPv4 and IPv6 combined),
+// kernel socket totals from sockstat, and per-second protocol counter
+// rates from snmp and netstat. TCPRetransPercent is retransmitted segments
+// as a share of segments sent.
+type SocketStats struct {
+	TCPEstablished     uint64
+	TCPSynSent         uint64
+	TCPSynRecv         uint64
+	TCPFinWait         uint64
+	TCPTimeWait        uint64
+	TCPCloseWait       uint64
+	TCPLastAck         uint64
+	TCPClosing         uint64
+	TCPListen          uint64
+	SocketsUsed        uint64
+	TCPInUse           uint64
+	TCPOrphans         uint64
+	TCPTimeWaitBuckets uint64
+	TCPAlloc           uint64
+	TCPMemPages        uint64
+	UDPInUse           uint64
+	TCPActiveOpensPS   float64
+	TCPPassiveOpensPS  float64
+	TCPAttemptFailsPS  float64
+	TCPEstabResetsPS   float64
+	TCPOutSegsPS       float64
+	TCPRetransSegsPS   float64
+	TCPRetransPercent  float64
+	ListenOverflowsPS  float64
+	ListenDropsPS      float64
+	SyncookiesSentPS   float64
+	UDPInErrorsPS      float64
+	UDPRcvbufErrorsPS  float64
+	UDPSndbufErrorsPS  float64
+}
+
+// ThermalStats holds temperature sensors and CPU thermal throttle events
+// since the previous sample. Temperatures are in degrees Celsius; Max and
+// Crit are 0 when the sensor exports no limit.
+type ThermalStats struct {
+	Sensors          []TemperatureSensor
+	CoreThrottles    uint64
+	PackageThrottles uint64
+	ThrottledCPUs    []string
+}
+
+type TemperatureSensor struct {
+	Name        string
+	Source      string
+	TempCelsius float64
+	MaxCelsius  float64
+	CritCelsius float64
+}
+
+// OOMVictim is a process the kernel OOM killer reported killing.
+type OOMVictim struct {
+	PID  int
+	Name string
+}
+
+// KernelEvent is a kernel log message matched by a kernel_log rule.
+type KernelEvent struct {
+	Rule    string
+	Message string
+}
+
+// LogMatchStats counts the lines of a followed log file that matched one
+// rule since the previous sample. Lines keeps the first few matches.
+type LogMatchStats struct {
+	File      string
+	Path      string
+	Rule      string
+	Critical  bool
+	Matches   uint64
+	MatchesPS float64
+	Lines     []string
+}
+
+// CgroupStats describes one cgroup v2 group. Limits of 0 mean unlimited,
+// and CPUUsagePercent is relative to a single core.
+type CgroupStats struct {
+	Path               string
+	CPUUsagePercent    float64
+	MemoryCurrentBytes uint64
+	MemoryMaxBytes     uint64
+	MemoryUsedPercent  float64
+	OOMEvents          uint64
+	OOMKills           uint64
+	NewOOMKills        uint64
+	IOReadBytesPS      float64
+	IOWriteBytesPS     float64
+	PidsCurrent        uint64
+	PidsMax            uint64
+	PidsUsedPercent    float64
+}
+
+// TextfileStats describes one *.prom file in the textfile directory. Error
+// is set when the file could not be read or parsed; its samples are then
+// left out.
+type TextfileStats struct {
+	Name    string
+	ModTime time.Time
+	AgeSec  float64
+	Samples int
+	Error   string
+}
+
+// ProbeResult is the latest outcome of an HTTP or TCP probe. LatencyMs is
+// the time to the response headers for HTTP and the connect time for TCP.
+// CertExpiry is zero unless an HTTPS probe saw a certificate.
+type ProbeResult struct {
+	Name       string
+	Target     string
+	CheckedAt  time.Time
+	Up         bool
+	Error      string
+	StatusCode int
+	LatencyMs  float64
+	CertExpiry time.Time
+	CertDays   float64
+}
+
+type TopProcesses struct {
+	ByCPU    []ProcessInfo `json:"by_cpu"`
+	ByMemory []ProcessInfo `json:"by_memory"`
+}
+
+type ProcessInfo struct {
+	PID        int     `json:"pid"`
+	PPID       int     `json:"ppid"`
+	Name       string  `json:"name"`
+	Cmdline    string  `json:"cmdline,omitempty"`
+	State      string  `json:"state"`
+	UID        int     `json:"uid"`
+	CPUPercent float64 `json:"cpu_percent"`
+	RSSBytes   uint64  `json:"rss_bytes"`
+	Threads    int     `json:"threads"`
 }
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"system-sentinel/internal/config"
+)
+
+type processWatch struct {
+	cfg      config.WatchedProcess
+	cmdline  *regexp.Regexp
+	uid      int
+	prevMain procKey
+}
+
+func newProcessWatches(cfgs []config.WatchedProcess) []*processWatch {
+	watches := make([]*processWatch, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		w := &processWatch{cfg: cfg, uid: -1}
+		if cfg.CmdlineRegex != "" {
+			w.cmdline = regexp.MustCompile(cfg.CmdlineRegex)
+		}
+		if cfg.User != "" {
+			w.uid = lookupUID(cfg.User)
+		}
+		watches = append(watches, w)
+	}
+	return watches
+}
+
+func lookupUID(name string) int {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		// Unknown users match nothing rather than everything.
+		return -2
+	}
+	uid, _ := strconv.Atoi(u.Uid)
+	return uid
+}
+
+func (p *processSource) collectWatchedProcesses() ([]WatchedProcessStats, error) {
+	if len(p.watches) == 0 {
+		return nil, nil
+	}
+
+	samples, err := p.procs.scanFresh(p.topWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]WatchedProcessStats, 0, len(p.watches))
+	for _, w := range p.watches {
+		stats = append(stats, p.evaluateWatch(w, samples))
+	}
+
+	p.procs.pruneCmdlines(samples)
+
+	return stats, nil
+}
+
+func (p *processSource) evaluateWatch(w *processWatch, samples []procSample) WatchedProcessStats {
+	stats := WatchedProcessStats{Name: w.cfg.Name}
+
+	pidfilePID := -1
+	if w.cfg.Pidfile != "" {
+		data, err := os.ReadFile(w.cfg.Pidfile)
+		if err != nil {
+			w.prevMain = procKey{}
+			return stats
+		}
+		pidfilePID, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			w.prevMain = procKey{}
+			return stats
+		}
+	}
+
+	var main procKey
+	for _, sample := range samples {
+		if pidfilePID >= 0 && sample.pid != pidfilePID {
+			continue
+		}
+		if w.cfg.MatchName != "" && sample.name != w.cfg.MatchName {
+			continue
+		}
+		if w.uid != -1 && p.procs.uid(sample.pid) != w.uid {
+			continue
+		}
+		if w.cmdline != nil && !w.cmdline.MatchString(p.procs.cmdline(sample)) {
+			continue
+		}
+
+		stats.Count++
+		stats.PIDs = append(stats.PIDs, sample.pid)
+		stats.CPUPercent += sample.cpuPercent
+		stats.RSSBytes += sample.rssBytes
+		stats.Threads += sample.threads
+		stats.OpenFDs += p.procs.openFDs(sample.pid)
+
+		if main.pid == 0 || sample.startTime < main.startTime {
+			main = procKey{pid: sample.pid, startTime: sample.startTime}
+		}
+	}
+
+	stats.MainPID = main.pid
+	stats.Restarted = w.prevMain.pid != 0 && main.pid != 0 && main != w.prevMain
+	w.prevMain = main
+
+	return stats
+}
+
+func (s *processScanner) uid(pid int) int {
+	info, err := os.Stat(filepath.Join(s.procRoot, strconv.Itoa(pid)))
+	if err != nil {
+		return -1
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1
+	}
+	return int(st.Uid)
+}
+
+func (s *processScanner) openFDs(pid int) int {
+	entries, err := os.ReadDir(filepath.Join(s.procRoot, strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
+
+// cmdline returns a process's command line, cached per PID and start time
+// since it rarely changes and regex watches would otherwise reread it for
+// every process on every sample.
+func (s *processScanner) cmdline(sample procSample) string {
+	key := procKey{pid: sample.pid, startTime: sample.startTime}
+	if cmd, ok := s.cmdlines[key]; ok {
+		return cmd
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.procRoot, strconv.Itoa(sample.pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	cmd := strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+
+	if s.cmdlines == nil {
+		s.cmdlines = make(map[procKey]string)
+	}
+	s.cmdlines[key] = cmd
+	return cmd
+}
+
+func (s *processScanner) pruneCmdlines(samples []procSample) {
+	if len(s.cmdlines) == 0 {
+		return
+	}
+	live := make(map[procKey]bool, len(samples))
+	for _, sample := range samples {
+		live[procKey{pid: sample.pid, startTime: sample.startTime}] = true
+	}
+	for key := range s.cmdlines {
+		if !live[key] {
+			delete(s.cmdlines, key)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+	"system-sentinel/internal/scripts"
+)
+
+// Register adds a source for every configured exec plugin.
+func Register(registry *metrics.Registry, cfg *config.Config) {
+	for _, plugin := range cfg.ExecPlugins {
+		registry.Register(NewExecSource(plugin, cfg.Env))
+	}
+}
+
+// ExecSource runs an external command on its own interval and reports the
+// samples parsed from its latest output, labelled plugin=<name>.
+type ExecSource struct {
+	cfg config.ExecPlugin
+	env map[string]string
+
+	mu      sync.Mutex
+	samples []metrics.Sample
+	err     error
+}
+
+// NewExecSource builds a plugin source. The command sees the daemon's
+// environment plus the config env map, the plugin's own env, and
+// SYS_PLUGIN_NAME.
+func NewExecSource(cfg config.ExecPlugin, baseEnv map[string]string) *ExecSource {
+	env := make(map[string]string, len(baseEnv)+len(cfg.Env)+1)
+	for k, v := range baseEnv {
+		env[k] = v
+	}
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	env["SYS_PLUGIN_NAME"] = cfg.Name
+
+	return &ExecSource{cfg: cfg, env: env}
+}
+
+func (s *ExecSource) Name() string { return "exec:" + s.cfg.Name }
+
+// Init checks that the command exists and starts running it in the
+// background, first right away and then every interval_sec.
+func (s *ExecSource) Init() error {
+	if _, err := exec.LookPath(s.cfg.Command); err != nil {
+		return err
+	}
+	go s.loop()
+	return nil
+}
+
+func (s *ExecSource) loop() {
+	ticker := time.NewTicker(time.Duration(s.cfg.IntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		s.run()
+		<-ticker.C
+	}
+}
+
+func (s *ExecSource) run() {
+	timeout := time.Duration(s.cfg.TimeoutSec) * time.Second
+	out, err := scripts.RunCommand(timeout, s.env, s.cfg.Command, s.cfg.Args...)
+
+	var samples []metrics.Sample
+	if err == nil {
+		samples, err = s.parse(out)
+	}
+	for i := range samples {
+		if samples[i].Labels == nil {
+			samples[i].Labels = make(map[string]string, 1)
+		}
+		samples[i].Labels["plugin"] = s.cfg.Name
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = samples
+	s.err = err
+}
+
+func (s *ExecSource) parse(out []byte) ([]metrics.Sample, error) {
+	var samples []metrics.Sample
+	var err error
+	switch s.cfg.Format {
+	case "prometheus":
+		samples, err = metrics.ParsePrometheusText(bytes.NewReader(out))
+	default:
+		samples, err = metrics.ParseKeyValue(bytes.NewReader(out))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse output: %w", err)
+	}
+	if err := metrics.CheckSampleNames(samples); err != nil {
+		return nil, fmt.Errorf("parse output: %w", err)
+	}
+	return samples, nil
+}
+
+// Collect returns the samples from the latest run. A failed run is reported
+// once and leaves no samples until the next run succeeds.
+func (s *ExecSource) Collect(snap *metrics.MetricsSnapshot) ([]metrics.Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		err := s.err
+		s.err = nil
+		return nil, err
+	}
+	return s.samples, nil
+}
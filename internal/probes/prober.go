@@ -0,0 +1,140 @@
+package probes
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+// maxBodyBytes caps how much of an HTTP response is read for body_regex.
+const maxBodyBytes = 1 << 20
+
+// Prober checks a single HTTP(S) URL or TCP address.
+type Prober struct {
+	probe   config.Probe
+	timeout time.Duration
+	body    *regexp.Regexp
+	client  *http.Client
+}
+
+// NewProber builds a prober for probe. HTTP probes open a new connection
+// for every check, bypass any proxy, and do not follow redirects, so the
+// latency and status code are those of the endpoint itself.
+func NewProber(probe config.Probe) (*Prober, error) {
+	p := &Prober{
+		probe:   probe,
+		timeout: time.Duration(probe.TimeoutSec) * time.Second,
+	}
+	if probe.BodyRegex != "" {
+		re, err := regexp.Compile(probe.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("probe %s: body_regex: %w", probe.Name, err)
+		}
+		p.body = re
+	}
+	if probe.URL != "" {
+		p.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: probe.InsecureSkipVerify},
+				DisableKeepAlives: true,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return p, nil
+}
+
+// Check runs the probe once and returns its result.
+func (p *Prober) Check() metrics.ProbeResult {
+	result := metrics.ProbeResult{
+		Name:      p.probe.Name,
+		CheckedAt: time.Now(),
+	}
+
+	var err error
+	if p.probe.URL != "" {
+		result.Target = p.probe.URL
+		err = p.checkHTTP(&result)
+	} else {
+		result.Target = p.probe.Address
+		err = p.checkTCP(&result)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Up = true
+	return result
+}
+
+func (p *Prober) checkHTTP(result *metrics.ProbeResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, p.probe.Method, p.probe.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000.0
+	result.StatusCode = resp.StatusCode
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.CertExpiry = resp.TLS.PeerCertificates[0].NotAfter
+		result.CertDays = result.CertExpiry.Sub(result.CheckedAt).Hours() / 24
+	}
+
+	if !p.statusOK(resp.StatusCode) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if p.body != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+		if err != nil {
+			return fmt.Errorf("read body: %w", err)
+		}
+		if !p.body.Match(body) {
+			return fmt.Errorf("body does not match %q", p.probe.BodyRegex)
+		}
+	}
+
+	return nil
+}
+
+func (p *Prober) statusOK(code int) bool {
+	if len(p.probe.ExpectStatus) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, expected := range p.probe.ExpectStatus {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Prober) checkTCP(result *metrics.ProbeResult) error {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", p.probe.Address, p.timeout)
+	if err != nil {
+		return err
+	}
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000.0
+	return conn.Close()
+}
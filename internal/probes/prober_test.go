@@ -0,0 +1,157 @@
+package probes
+
+import (
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+func check(t *testing.T, probe config.Probe) metrics.ProbeResult {
+	t.Helper()
+	if probe.Method == "" && probe.URL != "" {
+		probe.Method = http.MethodGet
+	}
+	if probe.TimeoutSec == 0 {
+		probe.TimeoutSec = 5
+	}
+	prober, err := NewProber(probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prober.Check()
+}
+
+func TestHTTPStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/teapot":
+			w.WriteHeader(http.StatusTeapot)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		expect []int
+		up     bool
+		status int
+	}{
+		{"/ok", nil, true, 200},
+		{"/moved", nil, true, 302},
+		{"/fail", nil, false, 500},
+		{"/teapot", []int{418}, true, 418},
+		{"/ok", []int{204}, false, 200},
+	}
+	for _, tt := range tests {
+		result := check(t, config.Probe{Name: "web", URL: server.URL + tt.path, ExpectStatus: tt.expect})
+		if result.Up != tt.up || result.StatusCode != tt.status {
+			t.Errorf("%s expect %v: got up=%v status=%d (%s), want up=%v status=%d",
+				tt.path, tt.expect, result.Up, result.StatusCode, result.Error, tt.up, tt.status)
+		}
+		if result.Target != server.URL+tt.path {
+			t.Errorf("%s: target %q", tt.path, result.Target)
+		}
+		if !tt.up && !strings.Contains(result.Error, "unexpected status") {
+			t.Errorf("%s: error %q, want unexpected status", tt.path, result.Error)
+		}
+	}
+}
+
+func TestHTTPBodyRegex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"degraded"}`))
+	}))
+	defer server.Close()
+
+	result := check(t, config.Probe{Name: "health", URL: server.URL, BodyRegex: `"status":"ok"`})
+	if result.Up {
+		t.Fatal("got up, want down on body mismatch")
+	}
+	if result.StatusCode != 200 || !strings.Contains(result.Error, "body does not match") {
+		t.Errorf("got status %d and error %q", result.StatusCode, result.Error)
+	}
+
+	result = check(t, config.Probe{Name: "health", URL: server.URL, BodyRegex: `"status":"(ok|degraded)"`})
+	if !result.Up {
+		t.Errorf("got down (%s), want up on body match", result.Error)
+	}
+}
+
+func TestHTTPSCertDays(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	// The test certificate is self-signed, so the check fails unless
+	// verification is skipped.
+	result := check(t, config.Probe{Name: "tls", URL: server.URL})
+	if result.Up || result.CertDays != 0 {
+		t.Errorf("without insecure_skip_verify: got up=%v cert days %v, want a verification error", result.Up, result.CertDays)
+	}
+
+	result = check(t, config.Probe{Name: "tls", URL: server.URL, InsecureSkipVerify: true})
+	if !result.Up {
+		t.Fatalf("got down (%s), want up", result.Error)
+	}
+	notAfter := server.Certificate().NotAfter
+	if !result.CertExpiry.Equal(notAfter) {
+		t.Errorf("cert expiry: got %v, want %v", result.CertExpiry, notAfter)
+	}
+	want := notAfter.Sub(result.CheckedAt).Hours() / 24
+	if math.Abs(result.CertDays-want) > 0.01 {
+		t.Errorf("cert days: got %v, want %v", result.CertDays, want)
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	result := check(t, config.Probe{Name: "slow", URL: server.URL, TimeoutSec: 1})
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("check took %s, want about the 1s timeout", elapsed)
+	}
+	if result.Up || !strings.Contains(result.Error, "deadline exceeded") {
+		t.Errorf("got up=%v error %q, want a timeout", result.Up, result.Error)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	result := check(t, config.Probe{Name: "db", Address: address})
+	if !result.Up || result.Target != address {
+		t.Errorf("open port: got up=%v target %q (%s)", result.Up, result.Target, result.Error)
+	}
+
+	listener.Close()
+	result = check(t, config.Probe{Name: "db", Address: address})
+	if result.Up || result.Error == "" {
+		t.Errorf("closed port: got up=%v error %q, want a connect error", result.Up, result.Error)
+	}
+}
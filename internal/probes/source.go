@@ -0,0 +1,78 @@
+package probes
+
+import (
+	"sync"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+// Register adds a source running the configured probes, if there are any.
+func Register(registry *metrics.Registry, cfg *config.Config) {
+	if len(cfg.Probes) > 0 {
+		registry.Register(NewSource(cfg.Probes))
+	}
+}
+
+// Source runs each probe on its own interval in the background and reports
+// the latest result of every probe that has completed at least once.
+type Source struct {
+	probes  []config.Probe
+	probers []*Prober
+
+	mu      sync.Mutex
+	results map[string]metrics.ProbeResult
+}
+
+func NewSource(probes []config.Probe) *Source {
+	return &Source{
+		probes:  probes,
+		results: make(map[string]metrics.ProbeResult, len(probes)),
+	}
+}
+
+func (s *Source) Name() string { return "probes" }
+
+// Init builds the probers and starts them. Each probe runs right away and
+// then every interval_sec.
+func (s *Source) Init() error {
+	for _, probe := range s.probes {
+		prober, err := NewProber(probe)
+		if err != nil {
+			return err
+		}
+		s.probers = append(s.probers, prober)
+	}
+	for _, prober := range s.probers {
+		go s.loop(prober)
+	}
+	return nil
+}
+
+func (s *Source) loop(prober *Prober) {
+	ticker := time.NewTicker(time.Duration(prober.probe.IntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		result := prober.Check()
+
+		s.mu.Lock()
+		s.results[result.Name] = result
+		s.mu.Unlock()
+
+		<-ticker.C
+	}
+}
+
+func (s *Source) Collect(snap *metrics.MetricsSnapshot) ([]metrics.Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, prober := range s.probers {
+		if result, ok := s.results[prober.probe.Name]; ok {
+			snap.Probes = append(snap.Probes, result)
+		}
+	}
+	return nil, nil
+}
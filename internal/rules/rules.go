@@ -0,0 +1,185 @@
+// Package rules evaluates declarative threshold rules against the metrics
+// in a snapshot. The spike detector and alert engine share it for both the
+// rules declared in config and the sections translated to rules.
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+// Evaluate returns the spike or alert types raised by rules for current,
+// once each, in rule order.
+func Evaluate(rules []config.Rule, current, previous metrics.MetricsSnapshot) []string {
+	var types []string
+	for _, m := range Matches(rules, current, previous) {
+		types = append(types, m.Type)
+	}
+	return types
+}
+
+// Match is a spike or alert type with the first rule that raised it.
+type Match struct {
+	Type string
+	Rule *config.Rule
+}
+
+// Matches returns the spike or alert types raised by rules for current,
+// once each, in rule order, with the rule that raised them.
+func Matches(rules []config.Rule, current, previous metrics.MetricsSnapshot) []Match {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	e := newEvaluation(current, previous)
+
+	var matches []Match
+	seen := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		for _, m := range e.matches(*rule) {
+			t := Type(*rule, m.sample)
+			if !seen[t] {
+				seen[t] = true
+				matches = append(matches, Match{Type: t, Rule: rule})
+			}
+		}
+	}
+
+	return matches
+}
+
+// Value returns the value rule compared against its threshold for the first
+// series it matches in current: the sample's value, or its percent change
+// for relative rules. ok is false when the rule matches nothing.
+func Value(rule config.Rule, current, previous metrics.MetricsSnapshot) (value float64, ok bool) {
+	for _, m := range newEvaluation(current, previous).matches(rule) {
+		return m.value, true
+	}
+	return 0, false
+}
+
+// Type returns the spike or alert type a rule raises for a matching
+// series: "rule:<name>" for declared rules, and the section's own type for
+// translated ones.
+func Type(rule config.Rule, sample metrics.Sample) string {
+	if rule.Type == "" {
+		return "rule:" + rule.Name
+	}
+	if len(rule.TargetLabels) == 0 {
+		return rule.Type
+	}
+	return rule.Type + ":" + target(rule, sample)
+}
+
+// target returns the values of the rule's target labels on sample, joined
+// by "/".
+func target(rule config.Rule, sample metrics.Sample) string {
+	values := make([]string, len(rule.TargetLabels))
+	for i, label := range rule.TargetLabels {
+		values[i] = sample.Labels[label]
+	}
+	return strings.Join(values, "/")
+}
+
+// match is a series a rule matched, with the value it compared.
+type match struct {
+	sample metrics.Sample
+	value  float64
+}
+
+// evaluation holds the samples of one snapshot pair. The series values
+// that relative rules and requirements look up are indexed on first use.
+type evaluation struct {
+	samples        []metrics.Sample
+	values         map[string]float64
+	previous       metrics.MetricsSnapshot
+	previousValues map[string]float64
+}
+
+func newEvaluation(current, previous metrics.MetricsSnapshot) *evaluation {
+	return &evaluation{samples: metrics.SnapshotSamples(current), previous: previous}
+}
+
+// matches returns the series rule matches, in sample order.
+func (e *evaluation) matches(rule config.Rule) []match {
+	var matches []match
+	for _, sample := range metrics.MatchSamples(e.samples, rule.Metric, rule.Labels) {
+		if !selected(rule, sample) || !e.meets(rule.Requires, sample) {
+			continue
+		}
+
+		value := sample.Value
+		if rule.Relative {
+			if e.previousValues == nil {
+				e.previousValues = seriesValues(metrics.SnapshotSamples(e.previous))
+			}
+			prev, ok := e.previousValues[seriesKey(sample)]
+			if !ok || prev <= 0 {
+				continue
+			}
+			value = (value - prev) / prev * 100.0
+		}
+		if rule.Matches(value) {
+			matches = append(matches, match{sample, value})
+		}
+	}
+	return matches
+}
+
+// meets reports whether the series with sample's labels named by each
+// requirement exists and matches it.
+func (e *evaluation) meets(requires []config.SampleRule, sample metrics.Sample) bool {
+	if len(requires) == 0 {
+		return true
+	}
+	if e.values == nil {
+		e.values = seriesValues(e.samples)
+	}
+	for _, req := range requires {
+		value, ok := e.values[seriesKey(metrics.Sample{Name: req.Metric, Labels: sample.Labels})]
+		if !ok || !req.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// selected reports whether sample's target is covered by the override, or
+// the section defaults, that rule was translated from.
+func selected(rule config.Rule, sample metrics.Sample) bool {
+	if len(rule.Overrides) == 0 {
+		return true
+	}
+	return config.OverrideFor(rule.Overrides, target(rule, sample)) == rule.Override
+}
+
+func seriesValues(samples []metrics.Sample) map[string]float64 {
+	values := make(map[string]float64, len(samples))
+	for _, sample := range samples {
+		key := seriesKey(sample)
+		if _, ok := values[key]; !ok {
+			values[key] = sample.Value
+		}
+	}
+	return values
+}
+
+// seriesKey identifies a series across snapshots by its name and labels.
+func seriesKey(sample metrics.Sample) string {
+	keys := make([]string, 0, len(sample.Labels))
+	for k := range sample.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(sample.Name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "=" + sample.Labels[k])
+	}
+	return b.String()
+}
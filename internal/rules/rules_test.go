@@ -0,0 +1,115 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+// mountRule returns a filesystem rule translated from the section defaults
+// (override "") or the override key, with the section's override keys.
+func mountRule(override string, threshold float64) config.Rule {
+	return config.Rule{
+		SampleRule:   config.SampleRule{Metric: "filesystem_used_percent", Op: ">=", Threshold: threshold},
+		Type:         "filesystem",
+		TargetLabels: []string{"mount"},
+		Overrides:    []string{"/var", "/d*"},
+		Override:     override,
+	}
+}
+
+// TestEvaluate checks the types raised by declared and translated rules,
+// including relative rules, per-target overrides and requirements.
+func TestEvaluate(t *testing.T) {
+	previous := metrics.MetricsSnapshot{CPUUsagePercent: 40}
+	current := metrics.MetricsSnapshot{
+		CPUUsagePercent: 90,
+		MemUsedPercent:  50,
+		Filesystems: []metrics.FilesystemStats{
+			{MountPoint: "/", UsedPercent: 95},
+			{MountPoint: "/var", UsedPercent: 85},
+			{MountPoint: "/data", UsedPercent: 70},
+		},
+		Interfaces: []metrics.InterfaceStats{
+			{Name: "eth0", AdminUp: true},
+			{Name: "eth1"},
+			{Name: "eth2", AdminUp: true, LinkUp: true},
+		},
+	}
+
+	linkDown := config.Rule{
+		SampleRule:   config.SampleRule{Metric: "net_link_up", Op: "==", Threshold: 0},
+		Type:         "link",
+		TargetLabels: []string{"interface"},
+		Requires:     []config.SampleRule{{Metric: "net_admin_up", Op: "==", Threshold: 1}},
+	}
+
+	tests := []struct {
+		name  string
+		rules []config.Rule
+		want  []string
+	}{
+		{
+			name:  "declared rule with the default op",
+			rules: []config.Rule{{SampleRule: config.SampleRule{Name: "hot", Metric: "cpu_usage_percent", Threshold: 90}}},
+			want:  []string{"rule:hot"},
+		},
+		{
+			name: "labels narrow the series",
+			rules: []config.Rule{
+				{SampleRule: config.SampleRule{Name: "var", Metric: "filesystem_used_percent", Labels: map[string]string{"mount": "/var"}, Op: ">", Threshold: 90}},
+				{SampleRule: config.SampleRule{Name: "data", Metric: "filesystem_used_percent", Labels: map[string]string{"mount": "/data"}, Op: "<", Threshold: 75}},
+			},
+			want: []string{"rule:data"},
+		},
+		{
+			name: "relative",
+			rules: []config.Rule{
+				{SampleRule: config.SampleRule{Name: "cpu_doubled", Metric: "cpu_usage_percent", Op: ">=", Threshold: 100}, Relative: true},
+				{SampleRule: config.SampleRule{Name: "cpu_tripled", Metric: "cpu_usage_percent", Op: ">=", Threshold: 200}, Relative: true},
+			},
+			want: []string{"rule:cpu_doubled"},
+		},
+		{
+			name: "relative skips series without a previous value",
+			rules: []config.Rule{
+				{SampleRule: config.SampleRule{Name: "mem_rose", Metric: "mem_used_percent", Op: ">=", Threshold: 0}, Relative: true},
+			},
+			want: nil,
+		},
+		{
+			name:  "overrides",
+			rules: []config.Rule{mountRule("", 60), mountRule("/var", 90), mountRule("/d*", 75)},
+			want:  []string{"filesystem:/"},
+		},
+		{
+			name:  "override replaces the defaults",
+			rules: []config.Rule{mountRule("", 99), mountRule("/var", 80), mountRule("/d*", 60)},
+			want:  []string{"filesystem:/var", "filesystem:/data"},
+		},
+		{
+			name:  "requires",
+			rules: []config.Rule{linkDown},
+			want:  []string{"link:eth0"},
+		},
+		{
+			name: "each type once, in rule order",
+			rules: []config.Rule{
+				{SampleRule: config.SampleRule{Metric: "mem_used_percent", Op: ">=", Threshold: 50}, Type: "memory"},
+				{SampleRule: config.SampleRule{Metric: "cpu_usage_percent", Op: ">=", Threshold: 50}, Type: "cpu"},
+				{SampleRule: config.SampleRule{Metric: "cpu_usage_percent", Op: ">=", Threshold: 100}, Type: "cpu", Relative: true},
+			},
+			want: []string{"memory", "cpu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Evaluate(tt.rules, current, previous); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,38 +2,80 @@ package scripts
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"system-sentinel/internal/config"
+	"system-sentinel/internal/logging"
 	"system-sentinel/internal/metrics"
+	"system-sentinel/internal/rules"
 )
 
+// Runner runs the alert scripts. Runs are serialized, since each one
+// rewrites the shared env and processes files that its scripts read.
 type Runner struct {
 	cfg *config.Config
+	mu  sync.Mutex
 }
 
 func NewRunner(cfg *config.Config) *Runner {
 	return &Runner{cfg: cfg}
 }
 
-func (r *Runner) Execute(alertTypes []string, snap metrics.MetricsSnapshot) error {
-	if err := r.writeEnvFile(alertTypes, snap); err != nil {
+// Execute runs the scripts for alert types firing in snap, sampled after
+// previous. SYS_ALERT_SEVERITY holds the highest of their severities and
+// SYS_ALERT_SEVERITIES each type's as type=severity pairs.
+func (r *Runner) Execute(alertTypes []string, severities map[string]string, snap, previous metrics.MetricsSnapshot) error {
+	env := r.buildEnv("alert", alertTypes, snap, previous)
+	env["SYS_ALERT_SEVERITY"] = logging.EventSeverity(alertTypes, severities)
+	pairs := make([]string, 0, len(alertTypes))
+	for _, t := range alertTypes {
+		pairs = append(pairs, t+"="+severities[t])
+	}
+	env["SYS_ALERT_SEVERITIES"] = strings.Join(pairs, ",")
+	return r.run(env, snap)
+}
+
+// ExecuteResolved runs the scripts for an alert type whose condition
+// cleared, with SYS_EVENT_TYPE=resolved, the highest severity it reached,
+// and the time it fired and for how long. The rest of the env describes
+// the snapshot it resolved in.
+func (r *Runner) ExecuteResolved(alertType, severity string, firedAt time.Time, duration time.Duration, snap metrics.MetricsSnapshot) error {
+	// A resolved rule no longer matches, so there is no value to compare
+	// with the previous snapshot.
+	env := r.buildEnv("resolved", []string{alertType}, snap, metrics.MetricsSnapshot{})
+	env["SYS_ALERT_SEVERITY"] = severity
+	env["SYS_ALERT_FIRED_AT"] = firedAt.Format(time.RFC3339)
+	env["SYS_ALERT_DURATION_SEC"] = strconv.FormatFloat(duration.Seconds(), 'f', 0, 64)
+	return r.run(env, snap)
+}
+
+func (r *Runner) run(env map[string]string, snap metrics.MetricsSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writeEnvFile(env); err != nil {
 		return fmt.Errorf("failed to write env file: %w", err)
 	}
 
+	if snap.TopProcesses != nil {
+		if err := r.writeProcessesFile(snap.TopProcesses); err != nil {
+			return fmt.Errorf("failed to write processes file: %w", err)
+		}
+	}
+
 	scripts, err := r.findScripts()
 	if err != nil {
 		return fmt.Errorf("failed to find scripts: %w", err)
 	}
 
-	env := r.buildEnv(alertTypes, snap)
-
 	for _, script := range scripts {
 		if err := r.executeScript(script, env); err != nil {
 			return fmt.Errorf("script %s failed: %w", script, err)
@@ -43,9 +85,7 @@ func (r *Runner) Execute(alertTypes []string, snap metrics.MetricsSnapshot) erro
 	return nil
 }
 
-func (r *Runner) writeEnvFile(alertTypes []string, snap metrics.MetricsSnapshot) error {
-	env := r.buildEnv(alertTypes, snap)
-
+func (r *Runner) writeEnvFile(env map[string]string) error {
 	file, err := os.Create(r.cfg.Scripts.EnvFile)
 	if err != nil {
 		return err
@@ -53,7 +93,7 @@ func (r *Runner) writeEnvFile(alertTypes []string, snap metrics.MetricsSnapshot)
 	defer file.Close()
 
 	for key, value := range env {
-		if _, err := fmt.Fprintf(file, "%s=%s\n", key, value); err != nil {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", key, shellQuote(value)); err != nil {
 			return err
 		}
 	}
@@ -61,25 +101,292 @@ func (r *Runner) writeEnvFile(alertTypes []string, snap metrics.MetricsSnapshot)
 	return nil
 }
 
-func (r *Runner) buildEnv(alertTypes []string, snap metrics.MetricsSnapshot) map[string]string {
-	metric := "multi"
-	if len(alertTypes) == 1 {
-		metric = alertTypes[0]
+// shellQuote single-quotes value for the env file, so that sourcing it
+// assigns kernel messages and log lines verbatim instead of running what
+// they contain.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func (r *Runner) writeProcessesFile(top *metrics.TopProcesses) error {
+	data, err := json.MarshalIndent(top, "", "  ")
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(r.cfg.Scripts.ProcessesFile, append(data, '\n'), 0644)
+}
+
+func (r *Runner) buildEnv(eventType string, alertTypes []string, snap, previous metrics.MetricsSnapshot) map[string]string {
+	metric := logging.EventMetric(alertTypes)
 
 	env := map[string]string{
-		"SYS_TIMESTAMP":        snap.Timestamp.Format(time.RFC3339),
-		"SYS_EVENT_TYPE":       "alert",
-		"SYS_EVENT_METRIC":     metric,
-		"SYS_CPU_USAGE":        strconv.FormatFloat(snap.CPUUsagePercent, 'f', 2, 64),
-		"SYS_MEM_USED_PERCENT": strconv.FormatFloat(snap.MemUsedPercent, 'f', 2, 64),
-		"SYS_MEM_USED_BYTES":   strconv.FormatUint(snap.MemUsedBytes, 10),
-		"SYS_MEM_TOTAL_BYTES":  strconv.FormatUint(snap.MemTotalBytes, 10),
-		"SYS_NET_INTERFACE":    snap.NetInterface,
-		"SYS_NET_RX_BPS":       strconv.FormatFloat(snap.NetRxBytesPS, 'f', 2, 64),
-		"SYS_NET_TX_BPS":       strconv.FormatFloat(snap.NetTxBytesPS, 'f', 2, 64),
-		"SYS_NET_RX_MBPS":      strconv.FormatFloat(snap.NetRxMbps, 'f', 2, 64),
-		"SYS_NET_TX_MBPS":      strconv.FormatFloat(snap.NetTxMbps, 'f', 2, 64),
+		"SYS_TIMESTAMP":               snap.Timestamp.Format(time.RFC3339),
+		"SYS_EVENT_TYPE":              eventType,
+		"SYS_EVENT_METRIC":            metric,
+		"SYS_CPU_USAGE":               strconv.FormatFloat(snap.CPUUsagePercent, 'f', 2, 64),
+		"SYS_CPU_USER":                strconv.FormatFloat(snap.CPUTimes.UserPercent, 'f', 2, 64),
+		"SYS_CPU_SYSTEM":              strconv.FormatFloat(snap.CPUTimes.SystemPercent, 'f', 2, 64),
+		"SYS_CPU_IOWAIT":              strconv.FormatFloat(snap.CPUTimes.IOWaitPercent, 'f', 2, 64),
+		"SYS_CPU_STEAL":               strconv.FormatFloat(snap.CPUTimes.StealPercent, 'f', 2, 64),
+		"SYS_LOAD1":                   strconv.FormatFloat(snap.Load.Load1, 'f', 2, 64),
+		"SYS_LOAD5":                   strconv.FormatFloat(snap.Load.Load5, 'f', 2, 64),
+		"SYS_LOAD15":                  strconv.FormatFloat(snap.Load.Load15, 'f', 2, 64),
+		"SYS_PROCS_RUNNING":           strconv.FormatUint(snap.Load.ProcsRunning, 10),
+		"SYS_PROCS_BLOCKED":           strconv.FormatUint(snap.Load.ProcsBlocked, 10),
+		"SYS_FORKS_PS":                strconv.FormatFloat(snap.Load.ForksPS, 'f', 2, 64),
+		"SYS_FILE_HANDLES_PERCENT":    strconv.FormatFloat(snap.KernelTables.FileHandlesPercent, 'f', 2, 64),
+		"SYS_PIDS_PERCENT":            strconv.FormatFloat(snap.KernelTables.PIDsPercent, 'f', 2, 64),
+		"SYS_MEM_USED_PERCENT":        strconv.FormatFloat(snap.MemUsedPercent, 'f', 2, 64),
+		"SYS_MEM_USED_BYTES":          strconv.FormatUint(snap.MemUsedBytes, 10),
+		"SYS_MEM_TOTAL_BYTES":         strconv.FormatUint(snap.MemTotalBytes, 10),
+		"SYS_SWAP_USED_PERCENT":       strconv.FormatFloat(snap.SwapUsedPercent, 'f', 2, 64),
+		"SYS_SWAP_IN_PAGES_PS":        strconv.FormatFloat(snap.SwapInPagesPS, 'f', 2, 64),
+		"SYS_SWAP_OUT_PAGES_PS":       strconv.FormatFloat(snap.SwapOutPagesPS, 'f', 2, 64),
+		"SYS_MAJOR_FAULTS_PS":         strconv.FormatFloat(snap.MajorFaultsPS, 'f', 2, 64),
+		"SYS_MEM_DIRTY_BYTES":         strconv.FormatUint(snap.MemBreakdown.DirtyBytes, 10),
+		"SYS_NET_INTERFACE":           snap.NetInterface,
+		"SYS_NET_RX_BPS":              strconv.FormatFloat(snap.NetRxBytesPS, 'f', 2, 64),
+		"SYS_NET_TX_BPS":              strconv.FormatFloat(snap.NetTxBytesPS, 'f', 2, 64),
+		"SYS_NET_RX_MBPS":             strconv.FormatFloat(snap.NetRxMbps, 'f', 2, 64),
+		"SYS_NET_TX_MBPS":             strconv.FormatFloat(snap.NetTxMbps, 'f', 2, 64),
+		"SYS_TCP_ESTABLISHED":         strconv.FormatUint(snap.Sockets.TCPEstablished, 10),
+		"SYS_TCP_SYN_RECV":            strconv.FormatUint(snap.Sockets.TCPSynRecv, 10),
+		"SYS_TCP_TIME_WAIT":           strconv.FormatUint(snap.Sockets.TCPTimeWait, 10),
+		"SYS_TCP_CLOSE_WAIT":          strconv.FormatUint(snap.Sockets.TCPCloseWait, 10),
+		"SYS_TCP_RETRANS_PERCENT":     strconv.FormatFloat(snap.Sockets.TCPRetransPercent, 'f', 2, 64),
+		"SYS_TCP_LISTEN_OVERFLOWS_PS": strconv.FormatFloat(snap.Sockets.ListenOverflowsPS, 'f', 2, 64),
+		"SYS_UDP_RCVBUF_ERRORS_PS":    strconv.FormatFloat(snap.Sockets.UDPRcvbufErrorsPS, 'f', 2, 64),
+		"SYS_EVENT_REASONS":           strings.Join(alertTypes, ","),
+	}
+
+	if snap.Pressure.Available {
+		env["SYS_PSI_CPU_SOME_AVG10"] = strconv.FormatFloat(snap.Pressure.CPU.Some.Avg10, 'f', 2, 64)
+		env["SYS_PSI_MEMORY_SOME_AVG10"] = strconv.FormatFloat(snap.Pressure.Memory.Some.Avg10, 'f', 2, 64)
+		env["SYS_PSI_MEMORY_FULL_AVG10"] = strconv.FormatFloat(snap.Pressure.Memory.Full.Avg10, 'f', 2, 64)
+		env["SYS_PSI_IO_SOME_AVG10"] = strconv.FormatFloat(snap.Pressure.IO.Some.Avg10, 'f', 2, 64)
+		env["SYS_PSI_IO_FULL_AVG10"] = strconv.FormatFloat(snap.Pressure.IO.Full.Avg10, 'f', 2, 64)
+	}
+
+	if snap.KernelTables.ConntrackMax > 0 {
+		env["SYS_CONNTRACK_PERCENT"] = strconv.FormatFloat(snap.KernelTables.ConntrackPercent, 'f', 2, 64)
+	}
+	if snap.KernelTables.InotifyMaxUserWatches > 0 {
+		env["SYS_INOTIFY_WATCHES_PERCENT"] = strconv.FormatFloat(snap.KernelTables.InotifyWatchesPercent, 'f', 2, 64)
+	}
+
+	if snap.TopProcesses != nil {
+		env["SYS_TOP_PROCESSES_FILE"] = r.cfg.Scripts.ProcessesFile
+		env["SYS_TOP_CPU"] = formatProcesses(snap.TopProcesses.ByCPU)
+		env["SYS_TOP_MEM"] = formatProcesses(snap.TopProcesses.ByMemory)
+	}
+
+	if names := alertTargets(alertTypes, "process"); len(names) > 0 {
+		env["SYS_PROCESS_NAME"] = strings.Join(names, ",")
+		for _, proc := range snap.WatchedProcesses {
+			if proc.Name != names[0] {
+				continue
+			}
+			pids := make([]string, 0, len(proc.PIDs))
+			for _, pid := range proc.PIDs {
+				pids = append(pids, strconv.Itoa(pid))
+			}
+			env["SYS_PROCESS_COUNT"] = strconv.Itoa(proc.Count)
+			env["SYS_PROCESS_PIDS"] = strings.Join(pids, ",")
+			env["SYS_PROCESS_RESTARTED"] = strconv.FormatBool(proc.Restarted)
+			env["SYS_PROCESS_CPU_PERCENT"] = strconv.FormatFloat(proc.CPUPercent, 'f', 2, 64)
+			env["SYS_PROCESS_RSS_BYTES"] = strconv.FormatUint(proc.RSSBytes, 10)
+			env["SYS_PROCESS_OPEN_FDS"] = strconv.Itoa(proc.OpenFDs)
+			env["SYS_PROCESS_THREADS"] = strconv.Itoa(proc.Threads)
+			break
+		}
+	}
+
+	if containsString(alertTypes, "oom_kill") {
+		env["SYS_OOM_KILLS"] = strconv.FormatUint(snap.OOMKills, 10)
+		pids := make([]string, 0, len(snap.OOMVictims))
+		names := make([]string, 0, len(snap.OOMVictims))
+		for _, v := range snap.OOMVictims {
+			pids = append(pids, strconv.Itoa(v.PID))
+			names = append(names, v.Name)
+		}
+		env["SYS_OOM_KILLED_PID"] = strings.Join(pids, ",")
+		env["SYS_OOM_KILLED_NAME"] = strings.Join(names, ",")
+	}
+
+	if names := alertTargets(alertTypes, "kernel"); len(names) > 0 {
+		env["SYS_KERNEL_RULE"] = strings.Join(names, ",")
+		for _, event := range snap.KernelEvents {
+			if event.Rule == names[0] {
+				env["SYS_KERNEL_MESSAGE"] = event.Message
+				break
+			}
+		}
+	}
+
+	if names := alertTargets(alertTypes, "log"); len(names) > 0 {
+		env["SYS_LOG_RULE"] = strings.Join(names, ",")
+		for _, m := range snap.LogMatches {
+			if m.File+"/"+m.Rule != names[0] {
+				continue
+			}
+			env["SYS_LOG_FILE"] = m.Path
+			env["SYS_LOG_MATCHES"] = strconv.FormatUint(m.Matches, 10)
+			env["SYS_LOG_MATCHES_PS"] = strconv.FormatFloat(m.MatchesPS, 'f', 2, 64)
+			if len(m.Lines) > 0 {
+				env["SYS_LOG_LINE"] = m.Lines[0]
+				if lines, err := json.Marshal(m.Lines); err == nil {
+					env["SYS_LOG_LINES"] = string(lines)
+				}
+			}
+			break
+		}
+	}
+
+	if sensors := alertTargets(alertTypes, "temperature"); len(sensors) > 0 {
+		env["SYS_TEMP_SENSOR"] = strings.Join(sensors, ",")
+		for _, sensor := range snap.Thermal.Sensors {
+			if sensor.Name == sensors[0] {
+				env["SYS_TEMP_CELSIUS"] = strconv.FormatFloat(sensor.TempCelsius, 'f', 1, 64)
+				env["SYS_TEMP_CRIT_CELSIUS"] = strconv.FormatFloat(sensor.CritCelsius, 'f', 1, 64)
+				break
+			}
+		}
+	}
+
+	if containsString(alertTypes, "throttle") {
+		env["SYS_THROTTLE_CORE_EVENTS"] = strconv.FormatUint(snap.Thermal.CoreThrottles, 10)
+		env["SYS_THROTTLE_PACKAGE_EVENTS"] = strconv.FormatUint(snap.Thermal.PackageThrottles, 10)
+		env["SYS_THROTTLE_CPUS"] = strings.Join(snap.Thermal.ThrottledCPUs, ",")
+	}
+
+	if names := alertTargets(alertTypes, "custom"); len(names) > 0 {
+		env["SYS_CUSTOM_RULE"] = strings.Join(names, ",")
+		for _, rule := range r.cfg.Alerts.Custom {
+			if rule.Name != names[0] {
+				continue
+			}
+			for _, sample := range metrics.MatchSamples(metrics.SnapshotSamples(snap), rule.Metric, rule.Labels) {
+				if rule.Matches(sample.Value) {
+					env["SYS_CUSTOM_METRIC"] = sample.Name
+					env["SYS_CUSTOM_VALUE"] = strconv.FormatFloat(sample.Value, 'f', -1, 64)
+					break
+				}
+			}
+			break
+		}
+	}
+
+	if names := alertTargets(alertTypes, "rule"); len(names) > 0 {
+		env["SYS_RULE_NAME"] = strings.Join(names, ",")
+		for _, rule := range r.cfg.Rules {
+			if rule.Name != names[0] {
+				continue
+			}
+			env["SYS_RULE_METRIC"] = rule.Metric
+			if value, ok := rules.Value(rule, snap, previous); ok {
+				env["SYS_RULE_VALUE"] = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+			break
+		}
+	}
+
+	if paths := alertTargets(alertTypes, "cgroup"); len(paths) > 0 {
+		env["SYS_CGROUP"] = strings.Join(paths, ",")
+		for _, cg := range snap.Cgroups {
+			if cg.Path != paths[0] {
+				continue
+			}
+			env["SYS_CGROUP_CPU_PERCENT"] = strconv.FormatFloat(cg.CPUUsagePercent, 'f', 2, 64)
+			env["SYS_CGROUP_MEMORY_CURRENT_BYTES"] = strconv.FormatUint(cg.MemoryCurrentBytes, 10)
+			env["SYS_CGROUP_MEMORY_MAX_BYTES"] = strconv.FormatUint(cg.MemoryMaxBytes, 10)
+			env["SYS_CGROUP_MEMORY_USED_PERCENT"] = strconv.FormatFloat(cg.MemoryUsedPercent, 'f', 2, 64)
+			env["SYS_CGROUP_OOM_KILLS"] = strconv.FormatUint(cg.OOMKills, 10)
+			env["SYS_CGROUP_PIDS_CURRENT"] = strconv.FormatUint(cg.PidsCurrent, 10)
+			break
+		}
+	}
+
+	if cores := alertTargets(alertTypes, "cpu_core"); len(cores) > 0 {
+		env["SYS_CPU_ALERT_CORES"] = strings.Join(cores, ",")
+	}
+
+	// The legacy network type, without a target, is raised for the single
+	// interface setting.
+	var ifaces []string
+	if containsString(alertTypes, "network") {
+		ifaces = append(ifaces, r.cfg.Interface)
+	}
+	for _, metric := range []string{"network", "packets", "link"} {
+		for _, name := range alertTargets(alertTypes, metric) {
+			if !containsString(ifaces, name) {
+				ifaces = append(ifaces, name)
+			}
+		}
+	}
+	if len(ifaces) > 0 {
+		env["SYS_NET_ALERT_INTERFACE"] = strings.Join(ifaces, ",")
+		for _, iface := range snap.Interfaces {
+			if iface.Name != ifaces[0] {
+				continue
+			}
+			env["SYS_NET_ALERT_RX_MBPS"] = strconv.FormatFloat(iface.RxMbps, 'f', 2, 64)
+			env["SYS_NET_ALERT_TX_MBPS"] = strconv.FormatFloat(iface.TxMbps, 'f', 2, 64)
+			env["SYS_NET_ALERT_PACKETS_PS"] = strconv.FormatFloat(iface.RxPacketsPS+iface.TxPacketsPS, 'f', 2, 64)
+			env["SYS_NET_ALERT_ERRORS_PS"] = strconv.FormatFloat(iface.RxErrorsPS+iface.TxErrorsPS, 'f', 2, 64)
+			env["SYS_NET_ALERT_DROPS_PS"] = strconv.FormatFloat(iface.RxDropsPS+iface.TxDropsPS, 'f', 2, 64)
+			env["SYS_NET_ALERT_OPERSTATE"] = iface.OperState
+			env["SYS_NET_ALERT_SPEED_MBPS"] = strconv.Itoa(iface.SpeedMbps)
+			break
+		}
+	}
+
+	if mountPoints := alertTargets(alertTypes, "filesystem"); len(mountPoints) > 0 {
+		env["SYS_FS_MOUNTPOINT"] = strings.Join(mountPoints, ",")
+		for _, fs := range snap.Filesystems {
+			if fs.MountPoint != mountPoints[0] {
+				continue
+			}
+			env["SYS_FS_USED_PERCENT"] = strconv.FormatFloat(fs.UsedPercent, 'f', 2, 64)
+			env["SYS_FS_FREE_BYTES"] = strconv.FormatUint(fs.FreeBytes, 10)
+			env["SYS_FS_INODES_USED_PERCENT"] = strconv.FormatFloat(fs.InodesUsedPercent, 'f', 2, 64)
+			break
+		}
+	}
+
+	if names := alertTargets(alertTypes, "probe"); len(names) > 0 {
+		env["SYS_PROBE"] = strings.Join(names, ",")
+		for _, probe := range snap.Probes {
+			if probe.Name != names[0] {
+				continue
+			}
+			env["SYS_PROBE_TARGET"] = probe.Target
+			env["SYS_PROBE_UP"] = strconv.FormatBool(probe.Up)
+			env["SYS_PROBE_LATENCY_MS"] = strconv.FormatFloat(probe.LatencyMs, 'f', 2, 64)
+			if probe.StatusCode != 0 {
+				env["SYS_PROBE_STATUS_CODE"] = strconv.Itoa(probe.StatusCode)
+			}
+			if probe.Error != "" {
+				env["SYS_PROBE_ERROR"] = probe.Error
+			}
+			if !probe.CertExpiry.IsZero() {
+				env["SYS_PROBE_CERT_DAYS"] = strconv.FormatFloat(probe.CertDays, 'f', 1, 64)
+			}
+			break
+		}
+	}
+
+	if names := alertTargets(alertTypes, "textfile"); len(names) > 0 {
+		env["SYS_TEXTFILE"] = strings.Join(names, ",")
+		for _, file := range snap.Textfiles {
+			if file.Name != names[0] {
+				continue
+			}
+			env["SYS_TEXTFILE_AGE_SEC"] = strconv.FormatFloat(file.AgeSec, 'f', 0, 64)
+			if file.Error != "" {
+				env["SYS_TEXTFILE_ERROR"] = file.Error
+			}
+			break
+		}
 	}
 
 	if r.cfg.Env != nil {
@@ -91,6 +398,39 @@ func (r *Runner) buildEnv(alertTypes []string, snap metrics.MetricsSnapshot) map
 	return env
 }
 
+// alertTargets returns the targets of alert types for the given metric, e.g.
+// "/var" for "filesystem:/var".
+func alertTargets(alertTypes []string, metric string) []string {
+	var targets []string
+	for _, t := range alertTypes {
+		name, target, ok := strings.Cut(t, ":")
+		if ok && name == metric {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// formatProcesses renders processes as comma-separated
+// pid:name:cpu_percent:rss_bytes entries for the script environment.
+func formatProcesses(procs []metrics.ProcessInfo) string {
+	parts := make([]string, 0, len(procs))
+	for _, p := range procs {
+		name := strings.NewReplacer(",", "_", ":", "_", " ", "_").Replace(p.Name)
+		parts = append(parts, fmt.Sprintf("%d:%s:%.2f:%d", p.PID, name, p.CPUPercent, p.RSSBytes))
+	}
+	return strings.Join(parts, ",")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Runner) findScripts() ([]string, error) {
 	entries, err := os.ReadDir(r.cfg.Scripts.Dir)
 	if err != nil {
@@ -123,22 +463,33 @@ func (r *Runner) findScripts() ([]string, error) {
 }
 
 func (r *Runner) executeScript(scriptPath string, env map[string]string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.cfg.Scripts.TimeoutSec)*time.Second)
+	_, err := RunCommand(time.Duration(r.cfg.Scripts.TimeoutSec)*time.Second, env, "/bin/bash", scriptPath)
+	return err
+}
+
+// RunCommand runs a command with env added to the daemon's environment and
+// kills it after timeout. It returns the command's standard output.
+func RunCommand(timeout time.Duration, env map[string]string, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "/bin/bash", scriptPath)
+	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Env = os.Environ()
 
 	for key, value := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	if err := cmd.Run(); err != nil {
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("exit code %d", exitErr.ExitCode())
+			return nil, fmt.Errorf("exit code %d", exitErr.ExitCode())
 		}
-		return err
+		return nil, err
 	}
 
-	return nil
+	return out, nil
 }
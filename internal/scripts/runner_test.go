@@ -0,0 +1,44 @@
+package scripts
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"system-sentinel/internal/config"
+)
+
+// TestEnvFileQuoting checks that sourcing the env file assigns each value
+// verbatim and runs none of it.
+func TestEnvFileQuoting(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Scripts.EnvFile = filepath.Join(dir, ".env")
+	r := NewRunner(cfg)
+
+	marker := filepath.Join(dir, "ran")
+	values := []string{
+		"42.00",
+		"",
+		"$(touch " + marker + ")",
+		"`touch " + marker + "`; touch " + marker,
+		"it's \"quoted\" \\ $HOME",
+		"first line\ntouch " + marker,
+	}
+
+	for _, value := range values {
+		if err := r.writeEnvFile(map[string]string{"SYS_LOG_LINE": value}); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command("/bin/bash", "-c", `. "$1" && printf %s "$SYS_LOG_LINE"`, "bash", cfg.Scripts.EnvFile).Output()
+		if err != nil {
+			t.Fatalf("sourcing env file for %q: %v", value, err)
+		}
+		if string(out) != value {
+			t.Errorf("SYS_LOG_LINE = %q, want %q", out, value)
+		}
+		if matches, _ := filepath.Glob(marker); len(matches) > 0 {
+			t.Fatalf("sourcing env file for %q ran a command", value)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package spikes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"system-sentinel/internal/config"
+	"system-sentinel/internal/metrics"
+)
+
+func loadConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// TestDetect checks the spike types raised over a series of samples,
+// including the types a clear threshold keeps raising.
+func TestDetect(t *testing.T) {
+	type sample struct {
+		cpu, mem float64
+		want     []string
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		samples []sample
+	}{
+		{
+			name: "absolute and relative",
+			config: `
+spikes:
+  cpu: {enabled: true, absolute_threshold: 80}
+  memory: {enabled: true, absolute_threshold: 90, relative_threshold: 50}
+`,
+			samples: []sample{
+				{cpu: 85, mem: 20, want: []string{"cpu"}},
+				{cpu: 50, mem: 40, want: []string{"memory"}},
+				{cpu: 50, mem: 50},
+				{cpu: 90, mem: 95, want: []string{"cpu", "memory"}},
+			},
+		},
+		{
+			name: "clear",
+			config: `
+spikes:
+  cpu:
+    enabled: true
+    absolute_threshold: 80
+    clear: {absolute_threshold: 70}
+`,
+			samples: []sample{
+				{cpu: 75},
+				{cpu: 85, want: []string{"cpu"}},
+				{cpu: 75, want: []string{"cpu"}},
+				{cpu: 65},
+				{cpu: 75},
+			},
+		},
+		{
+			name: "declared log rule",
+			config: `
+rules:
+  - name: busy
+    metric: cpu_usage_percent
+    threshold: 60
+    action: log
+  - name: paged
+    metric: cpu_usage_percent
+    threshold: 60
+`,
+			samples: []sample{
+				{cpu: 65, want: []string{"rule:busy"}},
+				{cpu: 50},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(loadConfig(t, tt.config))
+			previous := metrics.MetricsSnapshot{}
+			for i, s := range tt.samples {
+				current := metrics.MetricsSnapshot{
+					Timestamp:       time.Unix(1700000000+int64(i), 0),
+					CPUUsagePercent: s.cpu,
+					MemUsedPercent:  s.mem,
+				}
+				if got := d.Detect(current, previous); !reflect.DeepEqual(got, s.want) {
+					t.Errorf("sample %d: Detect() = %v, want %v", i, got, s.want)
+				}
+				previous = current
+			}
+		})
+	}
+}
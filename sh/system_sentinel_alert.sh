@@ -19,6 +19,14 @@ metric="${SYS_EVENT_METRIC:-unknown}"
 sys_timestamp="${SYS_TIMESTAMP:-}"
 sys_event_type="${SYS_EVENT_TYPE:-}"
 
+case "${SYS_ALERT_SEVERITY:-}" in
+  info) severity="LOW" ;;
+  warning) severity="MEDIUM" ;;
+  critical) severity="HIGH" ;;
+  page) severity="CRITICAL" ;;
+  *) severity="UNKNOWN" ;;
+esac
+
 cpu="${SYS_CPU_USAGE:-unknown}"
 mem_percent="${SYS_MEM_USED_PERCENT:-unknown}"
 mem_used="${SYS_MEM_USED_BYTES:-unknown}"
@@ -29,9 +37,22 @@ net_tx="${SYS_NET_TX_MBPS:-unknown}"
 
 ts_iso_now="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
 
-body=$(printf '{"severity":"HIGH","topic":"system-sentinel.alert","alert_key":"system-sentinel:%s:%s","message":"System sentinel alert for %s on %s","labels":{"env":"prod","service":"system-sentinel","source_host":"%s","server_ip":"%s","event_metric":"%s"},"details":{"sys_timestamp":"%s","sys_event_type":"%s","cpu_usage":"%s","mem_used_percent":"%s","mem_used_bytes":"%s","mem_total_bytes":"%s","net_interface":"%s","net_rx_mbps":"%s","net_tx_mbps":"%s"},"occurred_at":"%s","idempotency_key":"%s"}' \
-  "$metric" "$server_ip" \
+# A resolved run closes the alert with the same alert_key instead of
+# raising it again.
+if [ "$sys_event_type" = "resolved" ]; then
+  topic="system-sentinel.alert.resolved"
+  status="resolved"
+  message="System sentinel alert for $metric on $server_ip resolved"
+else
+  topic="system-sentinel.alert"
+  status="firing"
+  message="System sentinel alert for $metric on $server_ip"
+fi
+
+body=$(printf '{"severity":"%s","topic":"%s","status":"%s","alert_key":"system-sentinel:%s:%s","message":"%s","labels":{"env":"prod","service":"system-sentinel","source_host":"%s","server_ip":"%s","event_metric":"%s"},"details":{"sys_timestamp":"%s","sys_event_type":"%s","cpu_usage":"%s","mem_used_percent":"%s","mem_used_bytes":"%s","mem_total_bytes":"%s","net_interface":"%s","net_rx_mbps":"%s","net_tx_mbps":"%s"},"occurred_at":"%s","idempotency_key":"%s"}' \
+  "$severity" "$topic" "$status" \
   "$metric" "$server_ip" \
+  "$message" \
   "$host" "$server_ip" "$metric" \
   "$sys_timestamp" "$sys_event_type" "$cpu" "$mem_percent" "$mem_used" "$mem_total" "$net_if" "$net_rx" "$net_tx" \
   "$ts_iso_now" "$idempotency_key")